@@ -2,10 +2,13 @@ package dom
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
-	ErrAttrMustBeInt = errors.New("attr must be int")
+	ErrAttrMustBeInt         = errors.New("attr must be int")
+	ErrInvalidTextDecoration = errors.New("invalid text decoration")
 )
 
 // --------------------
@@ -16,6 +19,27 @@ type RawAttribute [2]string
 
 type RawAttributeList []RawAttribute
 
+// Get returns the value of the raw attribute name.
+func (l RawAttributeList) Get(name string) (string, bool) {
+	for _, attr := range l {
+		if attr[0] == name {
+			return attr[1], true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the value of the raw attribute name, adding it when missing.
+func (l *RawAttributeList) Set(name, value string) {
+	for i, attr := range *l {
+		if attr[0] == name {
+			(*l)[i][1] = value
+			return
+		}
+	}
+	*l = append(*l, RawAttribute{name, value})
+}
+
 // --------------------
 // 	      Attrs
 // --------------------
@@ -25,9 +49,10 @@ type Attributes struct {
 	Position        Position
 	Flex            int
 	Focusable       bool
+	FocusGroup      bool
 	FlexDirection   FlexDirection
-	Color           int
-	BackGroundColor int
+	Color           Color
+	BackGroundColor Color
 	Width           int
 	MaxWidth        int
 	MinWidth        int
@@ -40,7 +65,16 @@ type Attributes struct {
 	Top             int
 	Left            int
 	ID              string
+	Class           []string
 	TextAlign       TextAlign
+	WhiteSpace      WhiteSpace
+	TextOverflow    TextOverflow
+	FontWeight      FontWeight
+	FontStyle       FontStyle
+	TextDecoration  TextDecoration
+	DecorationStyle TextDecorationStyle
+	DecorationColor Color
+	Reverse         bool
 	Writable        bool
 	TextType        InputType
 	ZIndex          uint8
@@ -67,8 +101,9 @@ func WithDefaultAttributes() AttributesOpt {
 			Position:        Position_Relative,
 			FlexDirection:   FlexDirection_Row,
 			Focusable:       false,
-			Color:           0,
-			BackGroundColor: 0,
+			FocusGroup:      false,
+			Color:           ColorDefault,
+			BackGroundColor: ColorDefault,
 			Width:           0,
 			MaxWidth:        0,
 			MinWidth:        0,
@@ -94,14 +129,44 @@ func WithDefaultAttributes() AttributesOpt {
 //  Attributes Methods
 // --------------------
 
-func (a *Attributes) Parse(rawAttrs RawAttributeList) {
+// Parse applies the raw attributes in order. The inline style attribute is
+// applied last so that its declarations win over plain attributes. The
+// first error is returned after every attribute has been applied.
+func (a *Attributes) Parse(rawAttrs RawAttributeList) error {
+	var err error
 	for _, rawAttr := range rawAttrs {
-		a.AddRaw(rawAttr[0], rawAttr[1])
+		if AttrName(rawAttr[0]) == AttrName_Style {
+			continue
+		}
+		if e := a.AddRaw(rawAttr[0], rawAttr[1]); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, rawAttr := range rawAttrs {
+		if AttrName(rawAttr[0]) != AttrName_Style {
+			continue
+		}
+		if e := a.AddRaw(rawAttr[0], rawAttr[1]); e != nil && err == nil {
+			err = e
+		}
 	}
+	return err
 }
 
-func (a *Attributes) AddRaw(attr string, value string) {
+// AddStyle applies the declarations of an inline style such as
+// "color: 3; border: true".
+func (a *Attributes) AddStyle(style string) error {
+	decls, err := ParseDeclarations(style)
+	if err != nil {
+		return err
+	}
+	return a.Parse(decls)
+}
+
+func (a *Attributes) AddRaw(attr string, value string) error {
 	switch AttrName(attr) {
+	case AttrName_Style:
+		return a.AddStyle(value)
 	case AttrName_Display:
 		a.Display = stringToDisplay(value)
 	case AttrName_Position:
@@ -112,10 +177,20 @@ func (a *Attributes) AddRaw(attr string, value string) {
 		a.FlexDirection = stringToFlexDirection(value)
 	case AttrName_Focusable:
 		a.Focusable = stringToBool(value)
+	case AttrName_FocusGroup:
+		a.FocusGroup = stringToBool(value)
 	case AttrName_Color:
-		a.Color = stringToInt(value)
+		c, err := ParseColor(value)
+		if err != nil {
+			return err
+		}
+		a.Color = c
 	case AttrName_BackGroundColor:
-		a.BackGroundColor = stringToInt(value)
+		c, err := ParseColor(value)
+		if err != nil {
+			return err
+		}
+		a.BackGroundColor = c
 	case AttrName_Width:
 		a.Width = stringToInt(value)
 	case AttrName_MaxWidth:
@@ -140,8 +215,32 @@ func (a *Attributes) AddRaw(attr string, value string) {
 		a.Left = stringToInt(value)
 	case AttrName_ID:
 		a.ID = value
+	case AttrName_Class:
+		a.Class = strings.Fields(value)
 	case AttrName_TextAlign:
 		a.TextAlign = stringToTextAlign(value)
+	case AttrName_WhiteSpace:
+		a.WhiteSpace = stringToWhiteSpace(value)
+	case AttrName_TextOverflow:
+		a.TextOverflow = stringToTextOverflow(value)
+	case AttrName_FontWeight:
+		a.FontWeight = stringToFontWeight(value)
+	case AttrName_FontStyle:
+		a.FontStyle = stringToFontStyle(value)
+	case AttrName_TextDecoration:
+		return a.setTextDecoration(value, true)
+	case AttrName_TextDecorationLine:
+		return a.setTextDecoration(value, false)
+	case AttrName_TextDecorationStyle:
+		a.DecorationStyle = stringToTextDecorationStyle(value)
+	case AttrName_TextDecorationColor:
+		c, err := ParseColor(value)
+		if err != nil {
+			return err
+		}
+		a.DecorationColor = c
+	case AttrName_Reverse:
+		a.Reverse = stringToBool(value)
 	case AttrName_Writable:
 		a.Writable = stringToBool(value)
 	case AttrName_TextType:
@@ -149,6 +248,92 @@ func (a *Attributes) AddRaw(attr string, value string) {
 	case AttrName_ZIndex:
 		a.ZIndex = stringToUint8(value)
 	}
+	return nil
+}
+
+// setTextDecoration applies a text-decoration value such as
+// "underline wavy red" or "underline line-through". The shorthand also
+// takes the style and the color of the line, resetting those not given.
+func (a *Attributes) setTextDecoration(value string, shorthand bool) error {
+	var (
+		lines TextDecoration
+		style TextDecorationStyle
+		color Color
+	)
+	for _, token := range decorationTokens(value) {
+		token = strings.ToLower(token)
+		if line, ok := stringToTextDecoration(token); ok {
+			lines |= line
+			continue
+		}
+		if !shorthand {
+			return fmt.Errorf("%w: %q", ErrInvalidTextDecoration, value)
+		}
+		if s := stringToTextDecorationStyle(token); s != TextDecorationStyle_Solid || token == "solid" {
+			style = s
+			continue
+		}
+		c, err := ParseColor(token)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidTextDecoration, value)
+		}
+		color = c
+	}
+	a.TextDecoration = lines
+	if shorthand {
+		a.DecorationStyle = style
+		a.DecorationColor = color
+	}
+	return nil
+}
+
+// decorationTokens splits a text-decoration value on spaces, keeping
+// colors such as "rgb(1, 2, 3)" whole.
+func decorationTokens(value string) []string {
+	var (
+		tokens []string
+		depth  int
+		start  = -1
+	)
+	for i, r := range value {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == ' ' || r == '\t':
+			if depth == 0 {
+				if start >= 0 {
+					tokens = append(tokens, value[start:i])
+				}
+				start = -1
+				continue
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, value[start:])
+	}
+	return tokens
+}
+
+func (a *Attributes) HasClass(class string) bool {
+	for _, c := range a.Class {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// clone returns a copy of a that shares nothing with it.
+func (a *Attributes) clone() *Attributes {
+	c := *a
+	c.Class = append([]string(nil), a.Class...)
+	return &c
 }
 
 func (a *Attributes) InheritFrom(parent *Attributes) {
@@ -158,6 +343,12 @@ func (a *Attributes) InheritFrom(parent *Attributes) {
 	// a.FlexDirection = parent.FlexDirection
 	a.Color = parent.Color
 	a.BackGroundColor = parent.BackGroundColor
+	a.FontWeight = parent.FontWeight
+	a.FontStyle = parent.FontStyle
+	a.TextDecoration = parent.TextDecoration
+	a.DecorationStyle = parent.DecorationStyle
+	a.DecorationColor = parent.DecorationColor
+	a.Reverse = parent.Reverse
 	// a.Width = parent.Width
 	// a.MaxWidth = parent.MaxWidth
 	// a.MinWidth = parent.MinWidth
@@ -187,6 +378,7 @@ const (
 	AttrName_Flex            AttrName = "flex"
 	AttrName_FlexDirection   AttrName = "flex-direction"
 	AttrName_Focusable       AttrName = "focusable"
+	AttrName_FocusGroup      AttrName = "focus-group"
 	AttrName_Color           AttrName = "color"
 	AttrName_BackGroundColor AttrName = "background-color"
 	AttrName_Width           AttrName = "width"
@@ -201,10 +393,23 @@ const (
 	AttrName_Top             AttrName = "top"
 	AttrName_Left            AttrName = "left"
 	AttrName_ID              AttrName = "id"
+	AttrName_Class           AttrName = "class"
 	AttrName_TextAlign       AttrName = "text-align"
-	AttrName_Writable        AttrName = "writable"
-	AttrName_TextType        AttrName = "text-type"
-	AttrName_ZIndex          AttrName = "z-index"
+	AttrName_WhiteSpace      AttrName = "white-space"
+	AttrName_TextOverflow    AttrName = "text-overflow"
+	AttrName_FontWeight      AttrName = "font-weight"
+	AttrName_FontStyle       AttrName = "font-style"
+	AttrName_Reverse         AttrName = "reverse"
+
+	AttrName_TextDecoration      AttrName = "text-decoration"
+	AttrName_TextDecorationLine  AttrName = "text-decoration-line"
+	AttrName_TextDecorationStyle AttrName = "text-decoration-style"
+	AttrName_TextDecorationColor AttrName = "text-decoration-color"
+
+	AttrName_Writable AttrName = "writable"
+	AttrName_TextType AttrName = "text-type"
+	AttrName_ZIndex   AttrName = "z-index"
+	AttrName_Style    AttrName = "style"
 )
 
 type Display uint8
@@ -239,6 +444,29 @@ const (
 	TextAlign_Left TextAlign = iota
 	TextAlign_Center
 	TextAlign_Right
+	TextAlign_Justify
+)
+
+// WhiteSpace is how the spaces and line breaks of text are laid out.
+type WhiteSpace uint8
+
+const (
+	// WhiteSpace_Normal collapses runs of spaces and wraps lines.
+	WhiteSpace_Normal WhiteSpace = iota
+	// WhiteSpace_NoWrap collapses runs of spaces but never wraps lines.
+	WhiteSpace_NoWrap
+	// WhiteSpace_Pre keeps every space and never wraps lines.
+	WhiteSpace_Pre
+	// WhiteSpace_PreWrap keeps every space and wraps lines.
+	WhiteSpace_PreWrap
+)
+
+// TextOverflow is how text that does not fit its box ends.
+type TextOverflow uint8
+
+const (
+	TextOverflow_Clip TextOverflow = iota
+	TextOverflow_Ellipsis
 )
 
 type FlexDirection uint8
@@ -250,10 +478,39 @@ const (
 	FlexDirection_ColumnReverse
 )
 
+type FontWeight uint8
+
+const (
+	FontWeight_Normal FontWeight = iota
+	FontWeight_Bold
+	FontWeight_Dim
+)
+
+type FontStyle uint8
+
+const (
+	FontStyle_Normal FontStyle = iota
+	FontStyle_Italic
+)
+
+// TextDecoration is a set of lines, and blinking, drawn with the text.
 type TextDecoration uint8
 
 const (
-	TextDecoration_None TextDecoration = iota
-	TextDecoration_Underline
+	TextDecoration_None      TextDecoration = 0
+	TextDecoration_Underline TextDecoration = 1 << (iota - 1)
 	TextDecoration_LineThrough
+	TextDecoration_Blink
+)
+
+// TextDecorationStyle is the way the underline is drawn. Terminals that
+// cannot draw the others draw a solid line.
+type TextDecorationStyle uint8
+
+const (
+	TextDecorationStyle_Solid TextDecorationStyle = iota
+	TextDecorationStyle_Double
+	TextDecorationStyle_Wavy
+	TextDecorationStyle_Dotted
+	TextDecorationStyle_Dashed
 )
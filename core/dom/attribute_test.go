@@ -68,6 +68,98 @@ var attributeParseTestSuites = []attributeParseTestSuite{
 			Display: Display_Block,
 		},
 	},
+	{
+		name: "inline style",
+		input: RawAttributeList{
+			{
+				"style",
+				"color: 3; border: true; flex: 2",
+			},
+		},
+		expected: &Attributes{
+			Color:  PaletteColor(3),
+			Border: true,
+			Flex:   2,
+		},
+	},
+	{
+		name: "inline style wins over attributes",
+		input: RawAttributeList{
+			{
+				"style",
+				"color: 3",
+			},
+			{
+				"color",
+				"5",
+			},
+			{
+				"id",
+				"saman",
+			},
+		},
+		expected: &Attributes{
+			ID:    "saman",
+			Color: PaletteColor(3),
+		},
+	},
+	{
+		name: "text attributes",
+		input: RawAttributeList{
+			{
+				"font-weight",
+				"700",
+			},
+			{
+				"font-style",
+				"oblique",
+			},
+			{
+				"reverse",
+				"true",
+			},
+			{
+				"text-decoration",
+				"underline line-through curly rgb(255, 0, 0)",
+			},
+		},
+		expected: &Attributes{
+			FontWeight:      FontWeight_Bold,
+			FontStyle:       FontStyle_Italic,
+			Reverse:         true,
+			TextDecoration:  TextDecoration_Underline | TextDecoration_LineThrough,
+			DecorationStyle: TextDecorationStyle_Wavy,
+			DecorationColor: RGBColor(255, 0, 0),
+		},
+	},
+	{
+		name: "text decoration longhands",
+		input: RawAttributeList{
+			{
+				"style",
+				"text-decoration: underline double red; text-decoration-line: blink underline; text-decoration-style: dotted",
+			},
+		},
+		expected: &Attributes{
+			TextDecoration:  TextDecoration_Blink | TextDecoration_Underline,
+			DecorationStyle: TextDecorationStyle_Dotted,
+			DecorationColor: PaletteColor(1),
+		},
+	},
+	{
+		name: "text layout",
+		input: RawAttributeList{
+			{
+				"style",
+				"text-align: justify; white-space: pre-wrap; text-overflow: ellipsis",
+			},
+		},
+		expected: &Attributes{
+			TextAlign:    TextAlign_Justify,
+			WhiteSpace:   WhiteSpace_PreWrap,
+			TextOverflow: TextOverflow_Ellipsis,
+		},
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -79,3 +171,24 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStyleError(t *testing.T) {
+	actual := NewAttributes()
+	err := actual.Parse(RawAttributeList{
+		{"style", "color 3; border: true"},
+		{"id", "saman"},
+	})
+	assert.ErrorIs(t, err, ErrMalformedDeclaration)
+	assert.Equal(t, "saman", actual.ID)
+}
+
+func TestParseTextDecorationError(t *testing.T) {
+	actual := NewAttributes()
+	actual.AddRaw("text-decoration", "underline")
+	assert.ErrorIs(t, actual.AddRaw("text-decoration", "underline squiggly"), ErrInvalidTextDecoration)
+	assert.ErrorIs(t, actual.AddRaw("text-decoration-line", "underline red"), ErrInvalidTextDecoration)
+	assert.Equal(t, TextDecoration_Underline, actual.TextDecoration)
+
+	assert.NilError(t, actual.AddRaw("text-decoration", "none"))
+	assert.Equal(t, TextDecoration_None, actual.TextDecoration)
+}
@@ -0,0 +1,285 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidColor = errors.New("invalid color")
+
+// --------------------
+// 	      Color
+// --------------------
+
+// Color is a terminal color: the default color of the terminal, one of its
+// 256 palette colors or a 24-bit RGB color. The zero Color is the default.
+// Colors are converted to what the terminal can show when they are drawn.
+type Color uint32
+
+const ColorDefault Color = 0
+
+const (
+	colorPalette Color = 1 << 24
+	colorRGB     Color = 2 << 24
+	colorKind    Color = 0xff << 24
+)
+
+// PaletteColor returns the palette color n. The first 16 are the ANSI
+// colors, whose exact shades the terminal theme decides.
+func PaletteColor(n uint8) Color {
+	return colorPalette | Color(n)
+}
+
+func RGBColor(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+func (c Color) IsDefault() bool {
+	return c == ColorDefault
+}
+
+// Palette returns the palette index of c, if c is a palette color.
+func (c Color) Palette() (uint8, bool) {
+	return uint8(c), c&colorKind == colorPalette
+}
+
+// RGB returns the components of c, if c is an RGB color.
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c), c&colorKind == colorRGB
+}
+
+// String returns c in a form ParseColor accepts.
+func (c Color) String() string {
+	if n, ok := c.Palette(); ok {
+		if n == 0 {
+			// "0" stands for the default color
+			return "black"
+		}
+		return strconv.Itoa(int(n))
+	}
+	if r, g, b, ok := c.RGB(); ok {
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	return "default"
+}
+
+// ParseColor parses a color written as "default", a palette index from 1
+// to 255, "#rgb", "#rrggbb", "rgb(r, g, b)" or a name. As it always has,
+// "0" stands for the default color; palette color 0 is "black". The names
+// of the ANSI colors, e.g. "red" or "bright-red", stand for palette colors
+// so that they follow the terminal theme; the other CSS names for their
+// RGB values.
+func ParseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "default" || s == "" || s == "0":
+		return ColorDefault, nil
+	case strings.HasPrefix(s, "#"):
+		return parseHexColor(s)
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		return parseRGBColor(s)
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return PaletteColor(uint8(n)), nil
+	}
+	if n, ok := ansiColorNames[s]; ok {
+		return PaletteColor(n), nil
+	}
+	if rgb, ok := cssColorNames[s]; ok {
+		return RGBColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+	}
+	return ColorDefault, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+}
+
+func parseHexColor(s string) (Color, error) {
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return ColorDefault, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+	return RGBColor(uint8(n>>16), uint8(n>>8), uint8(n)), nil
+}
+
+func parseRGBColor(s string) (Color, error) {
+	parts := strings.Split(s[len("rgb("):len(s)-1], ",")
+	if len(parts) != 3 {
+		return ColorDefault, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+	var rgb [3]uint8
+	for i, p := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 8)
+		if err != nil {
+			return ColorDefault, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+		}
+		rgb[i] = uint8(n)
+	}
+	return RGBColor(rgb[0], rgb[1], rgb[2]), nil
+}
+
+var ansiColorNames = map[string]uint8{
+	"black":          0,
+	"red":            1,
+	"green":          2,
+	"yellow":         3,
+	"blue":           4,
+	"magenta":        5,
+	"cyan":           6,
+	"white":          7,
+	"bright-black":   8,
+	"bright-red":     9,
+	"bright-green":   10,
+	"bright-yellow":  11,
+	"bright-blue":    12,
+	"bright-magenta": 13,
+	"bright-cyan":    14,
+	"bright-white":   15,
+}
+
+// cssColorNames are the CSS named colors but those of the ANSI colors.
+var cssColorNames = map[string]uint32{
+	"aliceblue":            0xf0f8ff,
+	"antiquewhite":         0xfaebd7,
+	"aqua":                 0x00ffff,
+	"aquamarine":           0x7fffd4,
+	"azure":                0xf0ffff,
+	"beige":                0xf5f5dc,
+	"bisque":               0xffe4c4,
+	"blanchedalmond":       0xffebcd,
+	"blueviolet":           0x8a2be2,
+	"brown":                0xa52a2a,
+	"burlywood":            0xdeb887,
+	"cadetblue":            0x5f9ea0,
+	"chartreuse":           0x7fff00,
+	"chocolate":            0xd2691e,
+	"coral":                0xff7f50,
+	"cornflowerblue":       0x6495ed,
+	"cornsilk":             0xfff8dc,
+	"crimson":              0xdc143c,
+	"darkblue":             0x00008b,
+	"darkcyan":             0x008b8b,
+	"darkgoldenrod":        0xb8860b,
+	"darkgray":             0xa9a9a9,
+	"darkgreen":            0x006400,
+	"darkgrey":             0xa9a9a9,
+	"darkkhaki":            0xbdb76b,
+	"darkmagenta":          0x8b008b,
+	"darkolivegreen":       0x556b2f,
+	"darkorange":           0xff8c00,
+	"darkorchid":           0x9932cc,
+	"darkred":              0x8b0000,
+	"darksalmon":           0xe9967a,
+	"darkseagreen":         0x8fbc8f,
+	"darkslateblue":        0x483d8b,
+	"darkslategray":        0x2f4f4f,
+	"darkslategrey":        0x2f4f4f,
+	"darkturquoise":        0x00ced1,
+	"darkviolet":           0x9400d3,
+	"deeppink":             0xff1493,
+	"deepskyblue":          0x00bfff,
+	"dimgray":              0x696969,
+	"dimgrey":              0x696969,
+	"dodgerblue":           0x1e90ff,
+	"firebrick":            0xb22222,
+	"floralwhite":          0xfffaf0,
+	"forestgreen":          0x228b22,
+	"fuchsia":              0xff00ff,
+	"gainsboro":            0xdcdcdc,
+	"ghostwhite":           0xf8f8ff,
+	"gold":                 0xffd700,
+	"goldenrod":            0xdaa520,
+	"gray":                 0x808080,
+	"greenyellow":          0xadff2f,
+	"grey":                 0x808080,
+	"honeydew":             0xf0fff0,
+	"hotpink":              0xff69b4,
+	"indianred":            0xcd5c5c,
+	"indigo":               0x4b0082,
+	"ivory":                0xfffff0,
+	"khaki":                0xf0e68c,
+	"lavender":             0xe6e6fa,
+	"lavenderblush":        0xfff0f5,
+	"lawngreen":            0x7cfc00,
+	"lemonchiffon":         0xfffacd,
+	"lightblue":            0xadd8e6,
+	"lightcoral":           0xf08080,
+	"lightcyan":            0xe0ffff,
+	"lightgoldenrodyellow": 0xfafad2,
+	"lightgray":            0xd3d3d3,
+	"lightgreen":           0x90ee90,
+	"lightgrey":            0xd3d3d3,
+	"lightpink":            0xffb6c1,
+	"lightsalmon":          0xffa07a,
+	"lightseagreen":        0x20b2aa,
+	"lightskyblue":         0x87cefa,
+	"lightslategray":       0x778899,
+	"lightslategrey":       0x778899,
+	"lightsteelblue":       0xb0c4de,
+	"lightyellow":          0xffffe0,
+	"lime":                 0x00ff00,
+	"limegreen":            0x32cd32,
+	"linen":                0xfaf0e6,
+	"maroon":               0x800000,
+	"mediumaquamarine":     0x66cdaa,
+	"mediumblue":           0x0000cd,
+	"mediumorchid":         0xba55d3,
+	"mediumpurple":         0x9370db,
+	"mediumseagreen":       0x3cb371,
+	"mediumslateblue":      0x7b68ee,
+	"mediumspringgreen":    0x00fa9a,
+	"mediumturquoise":      0x48d1cc,
+	"mediumvioletred":      0xc71585,
+	"midnightblue":         0x191970,
+	"mintcream":            0xf5fffa,
+	"mistyrose":            0xffe4e1,
+	"moccasin":             0xffe4b5,
+	"navajowhite":          0xffdead,
+	"navy":                 0x000080,
+	"oldlace":              0xfdf5e6,
+	"olive":                0x808000,
+	"olivedrab":            0x6b8e23,
+	"orange":               0xffa500,
+	"orangered":            0xff4500,
+	"orchid":               0xda70d6,
+	"palegoldenrod":        0xeee8aa,
+	"palegreen":            0x98fb98,
+	"paleturquoise":        0xafeeee,
+	"palevioletred":        0xdb7093,
+	"papayawhip":           0xffefd5,
+	"peachpuff":            0xffdab9,
+	"peru":                 0xcd853f,
+	"pink":                 0xffc0cb,
+	"plum":                 0xdda0dd,
+	"powderblue":           0xb0e0e6,
+	"purple":               0x800080,
+	"rebeccapurple":        0x663399,
+	"rosybrown":            0xbc8f8f,
+	"royalblue":            0x4169e1,
+	"saddlebrown":          0x8b4513,
+	"salmon":               0xfa8072,
+	"sandybrown":           0xf4a460,
+	"seagreen":             0x2e8b57,
+	"seashell":             0xfff5ee,
+	"sienna":               0xa0522d,
+	"silver":               0xc0c0c0,
+	"skyblue":              0x87ceeb,
+	"slateblue":            0x6a5acd,
+	"slategray":            0x708090,
+	"slategrey":            0x708090,
+	"snow":                 0xfffafa,
+	"springgreen":          0x00ff7f,
+	"steelblue":            0x4682b4,
+	"tan":                  0xd2b48c,
+	"teal":                 0x008080,
+	"thistle":              0xd8bfd8,
+	"tomato":               0xff6347,
+	"turquoise":            0x40e0d0,
+	"violet":               0xee82ee,
+	"wheat":                0xf5deb3,
+	"whitesmoke":           0xf5f5f5,
+	"yellowgreen":          0x9acd32,
+}
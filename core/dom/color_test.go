@@ -0,0 +1,60 @@
+package dom_test
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"gotest.tools/v3/assert"
+)
+
+type colorParseTestSuite struct {
+	name     string
+	input    string
+	expected dom.Color
+	err      bool
+}
+
+var colorParseTestSuites = []colorParseTestSuite{
+	{name: "default", input: "default", expected: dom.ColorDefault},
+	{name: "palette", input: "56", expected: dom.PaletteColor(56)},
+	{name: "zero is the default", input: "0", expected: dom.ColorDefault},
+	{name: "black", input: "black", expected: dom.PaletteColor(0)},
+	{name: "hex", input: "#1E90ff", expected: dom.RGBColor(0x1e, 0x90, 0xff)},
+	{name: "short hex", input: "#f80", expected: dom.RGBColor(0xff, 0x88, 0x00)},
+	{name: "rgb", input: "rgb(10, 20,30)", expected: dom.RGBColor(10, 20, 30)},
+	{name: "ansi name", input: "Bright-Red", expected: dom.PaletteColor(9)},
+	{name: "css name", input: "rebeccapurple", expected: dom.RGBColor(0x66, 0x33, 0x99)},
+	{name: "palette out of range", input: "256", err: true},
+	{name: "bad hex", input: "#12345", err: true},
+	{name: "rgb out of range", input: "rgb(1, 2, 300)", err: true},
+	{name: "unknown name", input: "blurple", err: true},
+}
+
+func TestParseColor(t *testing.T) {
+	for _, suite := range colorParseTestSuites {
+		t.Run(suite.name, func(t *testing.T) {
+			c, err := dom.ParseColor(suite.input)
+			if suite.err {
+				assert.ErrorIs(t, err, dom.ErrInvalidColor)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, suite.expected, c)
+
+			// String gives the color back
+			again, err := dom.ParseColor(c.String())
+			assert.NilError(t, err)
+			assert.Equal(t, c, again)
+		})
+	}
+}
+
+func TestColorAttributes(t *testing.T) {
+	e, err := dom.NewElementFromString(`<element color="coral" style="background-color: #102030"></element>`)
+	assert.NilError(t, err)
+	assert.Equal(t, dom.RGBColor(0xff, 0x7f, 0x50), e.Attrs.Color)
+	assert.Equal(t, dom.RGBColor(0x10, 0x20, 0x30), e.Attrs.BackGroundColor)
+
+	assert.ErrorIs(t, e.SetAttribute("color", "nope"), dom.ErrInvalidColor)
+	assert.Equal(t, dom.RGBColor(0xff, 0x7f, 0x50), e.Attrs.Color)
+}
@@ -3,6 +3,9 @@ package dom
 import (
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/saman3d/samdoc/xml"
 )
@@ -12,15 +15,28 @@ var (
 )
 
 type Document struct {
-	Head *Head
-	Body *Element `xml:",any"`
+	Head        *Head
+	Body        *Element `xml:",any"`
+	Stylesheets []*Stylesheet
+	// BaseDir is the directory stylesheet links are resolved against. The
+	// working directory is used when it is empty.
+	BaseDir string
+	// AttrErrors lists the attribute values of the markup that could not be
+	// parsed. Those attributes keep their defaults; the document loads all
+	// the same.
+	AttrErrors []error
+
+	index *index
 }
 
-func NewDocumentFromReader(f io.Reader) (*Document, error) {
+func NewDocumentFromReader(f io.Reader, opts ...DocumentOpt) (*Document, error) {
 	doc := &Document{
 		Head: &Head{},
 		Body: NewElement("body"),
 	}
+	for _, opt := range opts {
+		*doc = opt(*doc)
+	}
 
 	fb, err := io.ReadAll(f)
 	if err != nil {
@@ -30,9 +46,99 @@ func NewDocumentFromReader(f io.Reader) (*Document, error) {
 	if err := xml.Unmarshal(fb, doc); err != nil && err != io.EOF {
 		return nil, err
 	}
+
+	if err := doc.loadStylesheets(); err != nil {
+		return nil, err
+	}
+	if len(doc.Stylesheets) != 0 {
+		doc.Restyle(doc.Body)
+	}
+	doc.Reindex()
 	return doc, nil
 }
 
+// --------------------
+//    Document Opts
+// --------------------
+
+type DocumentOpt func(Document) Document
+
+// WithBaseDir resolves relative stylesheet links against dir.
+func WithBaseDir(dir string) DocumentOpt {
+	return func(doc Document) Document {
+		doc.BaseDir = dir
+		return doc
+	}
+}
+
+// --------------------
+//    Document Styles
+// --------------------
+
+func (doc *Document) loadStylesheets() error {
+	for _, src := range doc.Head.Styles {
+		text := src.Text
+		if src.Href != "" {
+			path := src.Href
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(doc.BaseDir, path)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			text = string(b)
+		}
+		sheet, err := ParseStylesheet(text)
+		if err != nil {
+			return err
+		}
+		doc.Stylesheets = append(doc.Stylesheets, sheet)
+	}
+	return nil
+}
+
+// Restyle recomputes the attributes of el and its descendants. Each element
+// starts from the defaults, inherits from its parent, then takes the
+// matching stylesheet rules, its own attributes and finally the ones set
+// with SetAttribute, which win over everything else. Fields of Attrs
+// assigned directly, other than the id and classes, are computed afresh.
+func (doc *Document) Restyle(el *Element) {
+	el.Walk(doc.computeAttrs)
+}
+
+func (doc *Document) computeAttrs(el *Element) {
+	// Selectors look at the id and classes of el, which its raw attributes
+	// may have changed since they were last computed. Without raw ones, the
+	// id and classes code gave el are kept.
+	if id, ok := el.RawAttrs.Get(string(AttrName_ID)); ok {
+		el.Attrs.ID = id
+	}
+	if class, ok := el.RawAttrs.Get(string(AttrName_Class)); ok {
+		el.Attrs.Class = strings.Fields(class)
+	}
+
+	attrs := NewAttributes(WithDefaultAttributes())
+	attrs.ID, attrs.Class = el.Attrs.ID, el.Attrs.Class
+	if el.Parent != nil {
+		attrs.InheritFrom(el.Parent.Attrs)
+	}
+	// the declarations were checked when their stylesheet was parsed and
+	// the attributes when they were set
+	for _, m := range matchingRules(doc.Stylesheets, el) {
+		attrs.Parse(m.rule.Declarations)
+	}
+	attrs.Parse(el.RawAttrs)
+	attrs.Parse(el.overrides)
+	el.Attrs = attrs
+}
+
+// UsesPseudo reports whether the stylesheets have rules that depend on the
+// given pseudo class, so that elements must be restyled when it changes.
+func (doc *Document) UsesPseudo(p PseudoClass) bool {
+	return usesPseudo(doc.Stylesheets, p)
+}
+
 func (doc *Document) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 	for {
 		tok, err := d.Token()
@@ -52,6 +158,7 @@ func (doc *Document) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 				if err := doc.Body.XMLUnmarshal(d, tok); err != nil {
 					return err
 				}
+				doc.AttrErrors = append(doc.AttrErrors, doc.Body.takeAttrErrs()...)
 			}
 		case xml.EndTag:
 			if tok.Tagname != "html" {
@@ -63,7 +170,15 @@ func (doc *Document) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 }
 
 type Head struct {
-	Title string
+	Title  string
+	Styles []StyleSource
+}
+
+// StyleSource is a <style> block or a <link rel="stylesheet"> of the head,
+// kept in document order.
+type StyleSource struct {
+	Href string
+	Text string
 }
 
 func (head *Head) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
@@ -82,6 +197,31 @@ func (head *Head) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 					return err
 				}
 				head.Title = unel.Data
+			case "style":
+				var unel xml.UniversalElement
+				err := unel.XMLUnmarshal(d, tok)
+				if err != nil {
+					return err
+				}
+				head.Styles = append(head.Styles, StyleSource{Text: unel.Data})
+			case "link":
+				var unel xml.UniversalElement
+				err := unel.XMLUnmarshal(d, tok)
+				if err != nil {
+					return err
+				}
+				var rel, href string
+				for _, attr := range unel.Attrs {
+					switch attr[0] {
+					case "rel":
+						rel = attr[1]
+					case "href":
+						href = attr[1]
+					}
+				}
+				if rel == "stylesheet" && href != "" {
+					head.Styles = append(head.Styles, StyleSource{Href: href})
+				}
 			}
 		case xml.EndTag:
 			return nil
@@ -1,6 +1,7 @@
 package dom
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/saman3d/samdoc/xml"
@@ -8,38 +9,53 @@ import (
 )
 
 type Element struct {
-	Name     string
-	Children []*Element
-	Parent   *Element
-	Style    []rune
-	Content  string
-	Attrs    *Attributes
-	Boundry  Boundry
-	State    *ElementState
+	Name      string
+	Children  []*Element
+	Parent    *Element
+	Style     []rune
+	Content   string
+	RawAttrs  RawAttributeList
+	Attrs     *Attributes
+	Boundry   Boundry
+	State     *ElementState
+	Listeners Listeners
+
+	// overrides are the attributes set with SetAttribute, which Restyle
+	// applies after the stylesheets and the markup.
+	overrides RawAttributeList
+	// attrErrs are the attribute values of el and its descendants that
+	// XMLUnmarshal could not parse, until the parent or the document takes
+	// them.
+	attrErrs []error
 }
 
 func NewElement(name string) *Element {
-	return &Element{
+	el := &Element{
 		Name:     name,
 		Children: make([]*Element, 0),
 		Attrs:    NewAttributes(),
 		Boundry:  NewBoundry(-1, -1, -1, -1),
 		State:    NewElementState(),
 	}
+	return el
 }
 
+// NewElementFromString parses a single element from s. Unlike a document,
+// it fails on an attribute value that cannot be parsed.
 func NewElementFromString(s string) (*Element, error) {
 	el := NewElement("root")
 	err := xml.Unmarshal([]byte(s), el)
 	if err != nil {
 		return nil, err
 	}
+	if errs := el.takeAttrErrs(); len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
 	return el, nil
 }
 
 func MustParseElementFromString(s string) *Element {
-	el := NewElement("root")
-	err := xml.Unmarshal([]byte(s), el)
+	el, err := NewElementFromString(s)
 	if err != nil {
 		panic(err)
 	}
@@ -52,7 +68,17 @@ func (el *Element) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 	if el.Parent != nil {
 		el.Attrs.InheritFrom(el.Parent.Attrs)
 	}
-	el.Attrs.Parse(rawAttrsToAttibuteList(start.Attrs))
+	el.RawAttrs = rawAttrsToAttibuteList(start.Attrs)
+	for _, attr := range el.RawAttrs {
+		if AttrName(attr[0]) == AttrName_Style {
+			el.Style = []rune(attr[1])
+		}
+	}
+	// a bad value leaves the attribute at its default, but does not stop
+	// the rest of the markup from loading
+	if err := el.Attrs.Parse(el.RawAttrs); err != nil {
+		el.attrErrs = append(el.attrErrs, fmt.Errorf("%s: %w", start.Tagname, err))
+	}
 	for {
 		tok, err := d.Token()
 		if err != nil {
@@ -69,6 +95,7 @@ func (el *Element) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 			if err != nil {
 				return err
 			}
+			el.attrErrs = append(el.attrErrs, e.takeAttrErrs()...)
 			el.Children = append(el.Children, e)
 
 		case xml.EndTag:
@@ -80,10 +107,61 @@ func (el *Element) XMLUnmarshal(d *xml.XMLDecoder, start xml.StartTag) error {
 	}
 }
 
+func (el *Element) takeAttrErrs() []error {
+	errs := el.attrErrs
+	el.attrErrs = nil
+	return errs
+}
+
 func (el *Element) AppendChild(e *Element) {
 	el.Children = append(el.Children, e)
 }
 
+// SetAttribute sets the raw attribute name to value. The value is checked
+// before the element is changed; callers are expected to restyle el so
+// that its Attrs pick up the change. Attributes set this way win over the
+// stylesheets and over the inline style of the markup.
+func (el *Element) SetAttribute(name, value string) error {
+	if err := NewAttributes().AddRaw(name, value); err != nil {
+		return err
+	}
+	el.RawAttrs.Set(name, value)
+	el.overrides.Set(name, value)
+	if AttrName(name) == AttrName_Style {
+		el.Style = []rune(value)
+	}
+	return nil
+}
+
+// Clone returns a detached copy of el with fresh state and no listeners.
+// Children are copied too when deep is set.
+func (el *Element) Clone(deep bool) *Element {
+	c := NewElement(el.Name)
+	c.Content = el.Content
+	c.Style = append([]rune(nil), el.Style...)
+	c.RawAttrs = append(RawAttributeList(nil), el.RawAttrs...)
+	if el.Attrs != nil {
+		c.Attrs = el.Attrs.clone()
+	}
+	c.overrides = append(RawAttributeList(nil), el.overrides...)
+	if deep {
+		for _, child := range el.Children {
+			cc := child.Clone(true)
+			cc.Parent = c
+			c.Children = append(c.Children, cc)
+		}
+	}
+	return c
+}
+
+// Walk calls fn for el and every descendant, parents before children.
+func (el *Element) Walk(fn func(*Element)) {
+	fn(el)
+	for _, c := range el.Children {
+		c.Walk(fn)
+	}
+}
+
 func (el *Element) InheritChildrensAttr() {
 	for _, c := range el.Children {
 		c.Attrs.InheritFrom(el.Attrs)
@@ -195,6 +273,13 @@ func (b Boundry) Sum(b2 Boundry) Boundry {
 type ElementState struct {
 	ScrollX int
 	ScrollY int
+	Focused bool
+	Hovered bool
+	// Cursor is the rune offset of the caret in a writable element.
+	Cursor int
+	// Invalid is set when the value of a writable element does not match
+	// its text-type.
+	Invalid bool
 }
 
 func NewElementState() *ElementState {
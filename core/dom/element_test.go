@@ -1,6 +1,7 @@
 package dom_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/saman3d/samdoc/xml"
@@ -107,5 +108,66 @@ func TestElementFromString(t *testing.T) {
 	e, err := dom.NewElementFromString(`<element color="2"></element>`)
 	assert.Nil(t, err)
 	assert.Equal(t, "element", e.Name)
-	assert.Equal(t, 2, e.Attrs.Color)
+	assert.Equal(t, dom.PaletteColor(2), e.Attrs.Color)
+}
+
+func TestElementInlineStyle(t *testing.T) {
+	e, err := dom.NewElementFromString(`<element color="2" style="color: 4; z-index: 3"></element>`)
+	assert.Nil(t, err)
+	assert.Equal(t, dom.PaletteColor(4), e.Attrs.Color)
+	assert.Equal(t, uint8(3), e.Attrs.ZIndex)
+	assert.Equal(t, "color: 4; z-index: 3", string(e.Style))
+
+	_, err = dom.NewElementFromString(`<element style="color"></element>`)
+	assert.ErrorIs(t, err, dom.ErrMalformedDeclaration)
+}
+
+func TestDocumentAttrErrors(t *testing.T) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><head></head><body>
+		<p id="bad" color="blurple" height="2">bad</p>
+		<div style="border"><p id="good" color="3">good</p></div>
+	</body></html>`))
+	assert.Nil(t, err, "a bad attribute does not stop the document from loading")
+	assert.Len(t, doc.AttrErrors, 2)
+	assert.ErrorIs(t, doc.AttrErrors[0], dom.ErrInvalidColor)
+	assert.Equal(t, `p: invalid color: "blurple"`, doc.AttrErrors[0].Error())
+	assert.ErrorIs(t, doc.AttrErrors[1], dom.ErrMalformedDeclaration)
+
+	bad := doc.GetElementsByID("bad")[0]
+	assert.Equal(t, dom.ColorDefault, bad.Attrs.Color)
+	assert.Equal(t, 2, bad.Attrs.Height)
+	assert.Equal(t, dom.PaletteColor(3), doc.GetElementsByID("good")[0].Attrs.Color)
+}
+
+func TestElementSetAttribute(t *testing.T) {
+	e := dom.MustParseElementFromString(`<element color="2"></element>`)
+	assert.Nil(t, e.SetAttribute("color", "5"))
+	assert.Nil(t, e.SetAttribute("style", "border: true"))
+	assert.Equal(t, dom.RawAttributeList{{"color", "5"}, {"style", "border: true"}}, e.RawAttrs)
+	assert.Equal(t, "border: true", string(e.Style))
+
+	assert.ErrorIs(t, e.SetAttribute("style", "border"), dom.ErrMalformedDeclaration)
+	assert.Equal(t, "border: true", string(e.Style))
+}
+
+func TestElementClone(t *testing.T) {
+	e := dom.MustParseElementFromString(`<div class="a b"><p id="x">x</p></div>`)
+	e.AddEventListener(dom.EventType_Click, func(*dom.Event) {})
+	e.State.Focused = true
+
+	shallow := e.Clone(false)
+	assert.Equal(t, "div", shallow.Name)
+	assert.Empty(t, shallow.Children)
+	assert.Nil(t, shallow.Listeners)
+	assert.False(t, shallow.State.Focused)
+
+	deep := e.Clone(true)
+	assert.Equal(t, 1, len(deep.Children))
+	assert.Equal(t, deep, deep.Children[0].Parent)
+	assert.Equal(t, "x", deep.Children[0].Content)
+
+	deep.Attrs.Class[0] = "z"
+	deep.RawAttrs[0][1] = "z"
+	assert.Equal(t, []string{"a", "b"}, e.Attrs.Class)
+	assert.Equal(t, "a b", e.RawAttrs[0][1])
 }
@@ -0,0 +1,165 @@
+package dom
+
+// --------------------
+//      Event Types
+// --------------------
+
+type EventType string
+
+const (
+	EventType_Key     EventType = "key"
+	EventType_Click   EventType = "click"
+	EventType_Focus   EventType = "focus"
+	EventType_Blur    EventType = "blur"
+	EventType_Scroll  EventType = "scroll"
+	EventType_Resize  EventType = "resize"
+	EventType_Mount   EventType = "mount"
+	EventType_Unmount EventType = "unmount"
+	// EventType_Input is fired on a writable element after its value changed.
+	EventType_Input EventType = "input"
+	// EventType_Paste is fired on the focused element, or the body, when
+	// text is pasted. Preventing it keeps the text out of writable elements.
+	EventType_Paste EventType = "paste"
+)
+
+// Bubbles reports whether events of this type travel back up the tree
+// after reaching their target.
+func (t EventType) Bubbles() bool {
+	switch t {
+	case EventType_Focus, EventType_Blur, EventType_Resize, EventType_Mount, EventType_Unmount:
+		return false
+	default:
+		return true
+	}
+}
+
+type EventPhase uint8
+
+const (
+	EventPhase_None EventPhase = iota
+	EventPhase_Capturing
+	EventPhase_AtTarget
+	EventPhase_Bubbling
+)
+
+// --------------------
+//        Event
+// --------------------
+
+type Event struct {
+	Type EventType
+	// Target is the element the event was dispatched to.
+	Target *Element
+	// CurrentTarget is the element whose listeners are being run.
+	CurrentTarget *Element
+	Phase         EventPhase
+	// Detail carries whatever caused the event, usually a tty event.
+	Detail interface{}
+
+	stopped   bool
+	prevented bool
+}
+
+func NewEvent(t EventType, detail interface{}) *Event {
+	return &Event{
+		Type:   t,
+		Detail: detail,
+	}
+}
+
+// StopPropagation keeps the event from reaching any further element. The
+// remaining listeners of the current element still run.
+func (ev *Event) StopPropagation() {
+	ev.stopped = true
+}
+
+// PreventDefault cancels the action the engine would take after dispatch.
+func (ev *Event) PreventDefault() {
+	ev.prevented = true
+}
+
+func (ev *Event) PropagationStopped() bool {
+	return ev.stopped
+}
+
+func (ev *Event) DefaultPrevented() bool {
+	return ev.prevented
+}
+
+// --------------------
+//     Listeners
+// --------------------
+
+type EventListener func(ev *Event)
+
+type Listener struct {
+	Handler EventListener
+	Capture bool
+}
+
+type Listeners map[EventType][]Listener
+
+type ListenerOpt func(Listener) Listener
+
+// WithCapture runs the listener during the capture phase instead of the
+// bubble phase.
+func WithCapture() ListenerOpt {
+	return func(l Listener) Listener {
+		l.Capture = true
+		return l
+	}
+}
+
+func (el *Element) AddEventListener(t EventType, handler EventListener, opts ...ListenerOpt) {
+	l := Listener{Handler: handler}
+	for _, opt := range opts {
+		l = opt(l)
+	}
+	if el.Listeners == nil {
+		el.Listeners = make(Listeners)
+	}
+	el.Listeners[t] = append(el.Listeners[t], l)
+}
+
+// DispatchEvent runs the event through the capture phase from the root down
+// to el, then through el itself and, for bubbling events, back up to the
+// root. It reports false when a listener called PreventDefault.
+func (el *Element) DispatchEvent(ev *Event) bool {
+	ev.Target = el
+
+	var path []*Element
+	for p := el.Parent; p != nil; p = p.Parent {
+		path = append(path, p)
+	}
+
+	ev.Phase = EventPhase_Capturing
+	for i := len(path) - 1; i >= 0 && !ev.stopped; i-- {
+		path[i].invokeListeners(ev, true)
+	}
+
+	if !ev.stopped {
+		ev.Phase = EventPhase_AtTarget
+		el.invokeListeners(ev, true)
+		el.invokeListeners(ev, false)
+	}
+
+	if ev.Type.Bubbles() {
+		ev.Phase = EventPhase_Bubbling
+		for i := 0; i < len(path) && !ev.stopped; i++ {
+			path[i].invokeListeners(ev, false)
+		}
+	}
+
+	ev.Phase = EventPhase_None
+	ev.CurrentTarget = nil
+	return !ev.prevented
+}
+
+func (el *Element) invokeListeners(ev *Event, capture bool) {
+	ev.CurrentTarget = el
+	for _, l := range el.Listeners[ev.Type] {
+		if l.Capture == capture {
+			l.Handler(ev)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package dom_test
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEventTree() (root, middle, leaf *dom.Element) {
+	root = dom.MustParseElementFromString(`<root><middle><leaf></leaf></middle></root>`)
+	middle = root.Children[0]
+	leaf = middle.Children[0]
+	return
+}
+
+func record(log *[]string, name string) dom.EventListener {
+	return func(ev *dom.Event) {
+		*log = append(*log, name)
+	}
+}
+
+func TestDispatchEventPhases(t *testing.T) {
+	root, middle, leaf := newEventTree()
+	var log []string
+	root.AddEventListener(dom.EventType_Click, record(&log, "root capture"), dom.WithCapture())
+	root.AddEventListener(dom.EventType_Click, record(&log, "root bubble"))
+	middle.AddEventListener(dom.EventType_Click, record(&log, "middle capture"), dom.WithCapture())
+	middle.AddEventListener(dom.EventType_Click, record(&log, "middle bubble"))
+	leaf.AddEventListener(dom.EventType_Click, record(&log, "leaf"))
+
+	ok := leaf.DispatchEvent(dom.NewEvent(dom.EventType_Click, nil))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"root capture", "middle capture", "leaf", "middle bubble", "root bubble"}, log)
+}
+
+func TestDispatchEventNonBubbling(t *testing.T) {
+	root, _, leaf := newEventTree()
+	var log []string
+	root.AddEventListener(dom.EventType_Focus, record(&log, "root capture"), dom.WithCapture())
+	root.AddEventListener(dom.EventType_Focus, record(&log, "root bubble"))
+	leaf.AddEventListener(dom.EventType_Focus, record(&log, "leaf"))
+
+	leaf.DispatchEvent(dom.NewEvent(dom.EventType_Focus, nil))
+	assert.Equal(t, []string{"root capture", "leaf"}, log)
+}
+
+func TestDispatchEventStopAndPrevent(t *testing.T) {
+	root, middle, leaf := newEventTree()
+	var log []string
+	root.AddEventListener(dom.EventType_Key, record(&log, "root"))
+	middle.AddEventListener(dom.EventType_Key, func(ev *dom.Event) {
+		log = append(log, "middle")
+		assert.Equal(t, leaf, ev.Target)
+		assert.Equal(t, middle, ev.CurrentTarget)
+		ev.StopPropagation()
+		ev.PreventDefault()
+	})
+	middle.AddEventListener(dom.EventType_Key, record(&log, "middle second"))
+
+	ok := leaf.DispatchEvent(dom.NewEvent(dom.EventType_Key, nil))
+	assert.False(t, ok)
+	assert.Equal(t, []string{"middle", "middle second"}, log)
+}
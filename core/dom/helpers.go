@@ -71,21 +71,82 @@ func stringToTextAlign(s string) TextAlign {
 		return TextAlign_Center
 	case "right":
 		return TextAlign_Right
+	case "justify":
+		return TextAlign_Justify
 	default:
 		return TextAlign_Left
 	}
 }
 
-func stringToTextDecoration(s string) TextDecoration {
+func stringToWhiteSpace(s string) WhiteSpace {
+	switch s {
+	case "nowrap":
+		return WhiteSpace_NoWrap
+	case "pre":
+		return WhiteSpace_Pre
+	case "pre-wrap":
+		return WhiteSpace_PreWrap
+	default:
+		return WhiteSpace_Normal
+	}
+}
+
+func stringToTextOverflow(s string) TextOverflow {
+	switch s {
+	case "ellipsis":
+		return TextOverflow_Ellipsis
+	default:
+		return TextOverflow_Clip
+	}
+}
+
+func stringToTextDecoration(s string) (TextDecoration, bool) {
 	switch s {
 	case "none":
-		return TextDecoration_None
+		return TextDecoration_None, true
 	case "underline":
-		return TextDecoration_Underline
+		return TextDecoration_Underline, true
 	case "line-through":
-		return TextDecoration_LineThrough
+		return TextDecoration_LineThrough, true
+	case "blink":
+		return TextDecoration_Blink, true
+	default:
+		return TextDecoration_None, false
+	}
+}
+
+func stringToTextDecorationStyle(s string) TextDecorationStyle {
+	switch s {
+	case "double":
+		return TextDecorationStyle_Double
+	case "wavy", "curly":
+		return TextDecorationStyle_Wavy
+	case "dotted":
+		return TextDecorationStyle_Dotted
+	case "dashed":
+		return TextDecorationStyle_Dashed
+	default:
+		return TextDecorationStyle_Solid
+	}
+}
+
+func stringToFontWeight(s string) FontWeight {
+	switch s {
+	case "bold", "bolder", "600", "700", "800", "900":
+		return FontWeight_Bold
+	case "dim", "lighter", "100", "200", "300":
+		return FontWeight_Dim
+	default:
+		return FontWeight_Normal
+	}
+}
+
+func stringToFontStyle(s string) FontStyle {
+	switch s {
+	case "italic", "oblique":
+		return FontStyle_Italic
 	default:
-		return TextDecoration_None
+		return FontStyle_Normal
 	}
 }
 
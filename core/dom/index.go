@@ -0,0 +1,180 @@
+package dom
+
+import (
+	"sync"
+)
+
+// --------------------
+//       Index
+// --------------------
+
+// index maps ids, classes and tag names to the elements carrying them.
+// Lookups return elements in the order they were added to the document.
+type index struct {
+	mu      sync.RWMutex
+	ids     map[string][]*Element
+	classes map[string][]*Element
+	tags    map[string][]*Element
+	// keys remembers what each element was indexed under, so that it can
+	// be dropped even after its attributes changed.
+	keys map[*Element]indexKeys
+}
+
+type indexKeys struct {
+	id      string
+	tag     string
+	classes []string
+}
+
+func newIndex() *index {
+	return &index{
+		ids:     make(map[string][]*Element),
+		classes: make(map[string][]*Element),
+		tags:    make(map[string][]*Element),
+		keys:    make(map[*Element]indexKeys),
+	}
+}
+
+func (idx *index) add(el *Element) {
+	if _, ok := idx.keys[el]; ok {
+		idx.remove(el)
+	}
+	keys := indexKeys{
+		id:      el.Attrs.ID,
+		tag:     el.Name,
+		classes: append([]string(nil), el.Attrs.Class...),
+	}
+	if keys.id != "" {
+		idx.ids[keys.id] = append(idx.ids[keys.id], el)
+	}
+	for _, c := range keys.classes {
+		idx.classes[c] = append(idx.classes[c], el)
+	}
+	idx.tags[keys.tag] = append(idx.tags[keys.tag], el)
+	idx.keys[el] = keys
+}
+
+func (idx *index) remove(el *Element) {
+	keys, ok := idx.keys[el]
+	if !ok {
+		return
+	}
+	if keys.id != "" {
+		dropElement(idx.ids, keys.id, el)
+	}
+	for _, c := range keys.classes {
+		dropElement(idx.classes, c, el)
+	}
+	dropElement(idx.tags, keys.tag, el)
+	delete(idx.keys, el)
+}
+
+func (idx *index) lookup(m map[string][]*Element, key string) []*Element {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if len(m[key]) == 0 {
+		return nil
+	}
+	return append([]*Element(nil), m[key]...)
+}
+
+func dropElement(m map[string][]*Element, key string, el *Element) {
+	l := m[key]
+	for i, e := range l {
+		if e == el {
+			l = append(l[:i], l[i+1:]...)
+			break
+		}
+	}
+	if len(l) == 0 {
+		delete(m, key)
+		return
+	}
+	m[key] = l
+}
+
+// --------------------
+//   Document Index
+// --------------------
+
+func (doc *Document) idx() *index {
+	if doc.index == nil {
+		doc.Reindex()
+	}
+	return doc.index
+}
+
+// Reindex rebuilds the id, class and tag indexes from the body.
+func (doc *Document) Reindex() {
+	idx := newIndex()
+	if doc.Body != nil {
+		doc.Body.Walk(idx.add)
+	}
+	doc.index = idx
+}
+
+// Index adds el and its descendants to the indexes, replacing whatever they
+// were indexed under before. It must be called after an element is attached
+// to the document or after its id or classes changed.
+func (doc *Document) Index(el *Element) {
+	idx := doc.idx()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	el.Walk(idx.add)
+}
+
+// Unindex drops el and its descendants from the indexes.
+func (doc *Document) Unindex(el *Element) {
+	idx := doc.idx()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	el.Walk(idx.remove)
+}
+
+func (doc *Document) GetElementsByID(id string) []*Element {
+	idx := doc.idx()
+	return idx.lookup(idx.ids, id)
+}
+
+func (doc *Document) GetElementsByClassName(class string) []*Element {
+	idx := doc.idx()
+	return idx.lookup(idx.classes, class)
+}
+
+func (doc *Document) GetElementsByTagName(tag string) []*Element {
+	idx := doc.idx()
+	return idx.lookup(idx.tags, tag)
+}
+
+// --------------------
+//      Queries
+// --------------------
+
+// QuerySelectorAll returns the elements of the body matching the selector
+// list s in document order.
+func (doc *Document) QuerySelectorAll(s string) ([]*Element, error) {
+	list, err := ParseSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	var found []*Element
+	if doc.Body == nil {
+		return found, nil
+	}
+	doc.Body.Walk(func(el *Element) {
+		if list.Match(el) {
+			found = append(found, el)
+		}
+	})
+	return found, nil
+}
+
+// QuerySelector returns the first element of the body matching the selector
+// list s, or nil when there is none.
+func (doc *Document) QuerySelector(s string) (*Element, error) {
+	found, err := doc.QuerySelectorAll(s)
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
@@ -0,0 +1,87 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/stretchr/testify/assert"
+)
+
+const indexTemplate = `<html>
+<body>
+	<div id="list" class="panel">
+		<p id="one" class="item">one</p>
+		<p id="two" class="item selected">two</p>
+	</div>
+	<p id="three">three</p>
+</body>
+</html>`
+
+func ids(els []*dom.Element) []string {
+	var out []string
+	for _, el := range els {
+		out = append(out, el.Attrs.ID)
+	}
+	return out
+}
+
+func TestDocumentIndex(t *testing.T) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(indexTemplate))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"two"}, ids(doc.GetElementsByID("two")))
+	assert.Equal(t, []string{"one", "two"}, ids(doc.GetElementsByClassName("item")))
+	assert.Equal(t, []string{"one", "two", "three"}, ids(doc.GetElementsByTagName("p")))
+	assert.Nil(t, doc.GetElementsByID("missing"))
+
+	list := doc.GetElementsByID("list")[0]
+	extra := dom.MustParseElementFromString(`<p id="two" class="item">extra</p>`)
+	extra.Parent = list
+	list.AppendChild(extra)
+	doc.Index(extra)
+	assert.Equal(t, 2, len(doc.GetElementsByID("two")))
+
+	doc.Unindex(extra)
+	assert.Equal(t, []*dom.Element{list.Children[1]}, doc.GetElementsByID("two"))
+
+	doc.Unindex(list)
+	assert.Nil(t, doc.GetElementsByClassName("item"))
+	assert.Nil(t, doc.GetElementsByClassName("panel"))
+	assert.Equal(t, []string{"three"}, ids(doc.GetElementsByTagName("p")))
+}
+
+func TestDocumentIndexAttributeChange(t *testing.T) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(indexTemplate))
+	assert.Nil(t, err)
+
+	one := doc.GetElementsByID("one")[0]
+	one.Attrs.ID = "first"
+	one.Attrs.Class = []string{"selected"}
+	doc.Index(one)
+
+	assert.Nil(t, doc.GetElementsByID("one"))
+	assert.Equal(t, []*dom.Element{one}, doc.GetElementsByID("first"))
+	assert.Equal(t, []string{"two"}, ids(doc.GetElementsByClassName("item")))
+	assert.Equal(t, []string{"two", "first"}, ids(doc.GetElementsByClassName("selected")))
+}
+
+func TestDocumentQuerySelector(t *testing.T) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(indexTemplate))
+	assert.Nil(t, err)
+
+	found, err := doc.QuerySelectorAll("div > p.item, #three")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one", "two", "three"}, ids(found))
+
+	el, err := doc.QuerySelector(".panel .selected")
+	assert.Nil(t, err)
+	assert.Equal(t, "two", el.Attrs.ID)
+
+	el, err = doc.QuerySelector("table")
+	assert.Nil(t, err)
+	assert.Nil(t, el)
+
+	_, err = doc.QuerySelectorAll("p >")
+	assert.ErrorIs(t, err, dom.ErrInvalidSelector)
+}
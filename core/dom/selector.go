@@ -0,0 +1,260 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidSelector = errors.New("invalid selector")
+)
+
+// --------------------
+//      Selectors
+// --------------------
+
+type Combinator uint8
+
+const (
+	Combinator_None Combinator = iota
+	Combinator_Descendant
+	Combinator_Child
+)
+
+type PseudoClass string
+
+const (
+	PseudoClass_Focus PseudoClass = "focus"
+	PseudoClass_Hover PseudoClass = "hover"
+)
+
+// CompoundSelector matches a single element, e.g. "p#name.big:focus".
+type CompoundSelector struct {
+	Tag     string
+	ID      string
+	Classes []string
+	Pseudo  []PseudoClass
+	// Combinator relates this compound to the one before it.
+	Combinator Combinator
+}
+
+// Selector is a chain of compound selectors joined by combinators, e.g.
+// "table > trow p".
+type Selector []CompoundSelector
+
+// SelectorList is a comma separated group of selectors.
+type SelectorList []Selector
+
+// Specificity is the (ids, classes, tags) weight of a selector.
+type Specificity [3]int
+
+func (s Specificity) Less(o Specificity) bool {
+	for i := range s {
+		if s[i] != o[i] {
+			return s[i] < o[i]
+		}
+	}
+	return false
+}
+
+func ParseSelector(s string) (SelectorList, error) {
+	var list SelectorList
+	for _, part := range strings.Split(s, ",") {
+		sel, err := parseComplexSelector(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, s)
+		}
+		list = append(list, sel)
+	}
+	return list, nil
+}
+
+func MustParseSelector(s string) SelectorList {
+	list, err := ParseSelector(s)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+func parseComplexSelector(s string) (Selector, error) {
+	var sel Selector
+	comb := Combinator_None
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			if len(sel) > 0 && comb == Combinator_None {
+				comb = Combinator_Descendant
+			}
+			i++
+		case c == '>':
+			if len(sel) == 0 || comb == Combinator_Child {
+				return nil, ErrInvalidSelector
+			}
+			comb = Combinator_Child
+			i++
+		default:
+			if len(sel) > 0 && comb == Combinator_None {
+				return nil, ErrInvalidSelector
+			}
+			cs, n, err := parseCompoundSelector(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			cs.Combinator = comb
+			sel = append(sel, cs)
+			comb = Combinator_None
+			i += n
+		}
+	}
+	if len(sel) == 0 || comb == Combinator_Child {
+		return nil, ErrInvalidSelector
+	}
+	return sel, nil
+}
+
+func parseCompoundSelector(s string) (CompoundSelector, int, error) {
+	var cs CompoundSelector
+	i := 0
+	if i < len(s) && s[i] == '*' {
+		i++
+	} else {
+		n := identLen(s[i:])
+		cs.Tag = s[i : i+n]
+		i += n
+	}
+
+	for i < len(s) {
+		prefix := s[i]
+		if prefix != '#' && prefix != '.' && prefix != ':' {
+			break
+		}
+		n := identLen(s[i+1:])
+		if n == 0 {
+			return cs, 0, ErrInvalidSelector
+		}
+		name := s[i+1 : i+1+n]
+		switch prefix {
+		case '#':
+			cs.ID = name
+		case '.':
+			cs.Classes = append(cs.Classes, name)
+		case ':':
+			switch p := PseudoClass(name); p {
+			case PseudoClass_Focus, PseudoClass_Hover:
+				cs.Pseudo = append(cs.Pseudo, p)
+			default:
+				return cs, 0, ErrInvalidSelector
+			}
+		}
+		i += n + 1
+	}
+
+	if i == 0 {
+		return cs, 0, ErrInvalidSelector
+	}
+	return cs, i, nil
+}
+
+func identLen(s string) int {
+	for i, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// --------------------
+//      Matching
+// --------------------
+
+func (l SelectorList) Match(el *Element) bool {
+	for _, sel := range l {
+		if sel.Match(el) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPseudo reports whether any selector of the list depends on the given
+// pseudo class.
+func (l SelectorList) HasPseudo(p PseudoClass) bool {
+	for _, sel := range l {
+		for _, cs := range sel {
+			for _, sp := range cs.Pseudo {
+				if sp == p {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (s Selector) Match(el *Element) bool {
+	return s.matchFrom(len(s)-1, el)
+}
+
+func (s Selector) matchFrom(i int, el *Element) bool {
+	if !s[i].Match(el) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch s[i].Combinator {
+	case Combinator_Child:
+		return el.Parent != nil && s.matchFrom(i-1, el.Parent)
+	default:
+		for p := el.Parent; p != nil; p = p.Parent {
+			if s.matchFrom(i-1, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (s Selector) Specificity() Specificity {
+	var sp Specificity
+	for _, cs := range s {
+		if cs.ID != "" {
+			sp[0]++
+		}
+		sp[1] += len(cs.Classes) + len(cs.Pseudo)
+		if cs.Tag != "" {
+			sp[2]++
+		}
+	}
+	return sp
+}
+
+func (cs CompoundSelector) Match(el *Element) bool {
+	if cs.Tag != "" && cs.Tag != el.Name {
+		return false
+	}
+	if cs.ID != "" && cs.ID != el.Attrs.ID {
+		return false
+	}
+	for _, c := range cs.Classes {
+		if !el.Attrs.HasClass(c) {
+			return false
+		}
+	}
+	for _, p := range cs.Pseudo {
+		switch p {
+		case PseudoClass_Focus:
+			if !el.State.Focused {
+				return false
+			}
+		case PseudoClass_Hover:
+			if !el.State.Hovered {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package dom_test
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/stretchr/testify/assert"
+)
+
+var selectorTree = dom.MustParseElementFromString(`<body>
+	<table id="tbl" class="grid">
+		<trow class="row odd"><p id="a" class="cell">a</p></trow>
+		<trow class="row"><div><p id="b" class="cell big">b</p></div></trow>
+	</table>
+	<p id="c">c</p>
+</body>`)
+
+func findByID(el *dom.Element, id string) *dom.Element {
+	var found *dom.Element
+	el.Walk(func(el *dom.Element) {
+		if found == nil && el.Attrs.ID == id {
+			found = el
+		}
+	})
+	return found
+}
+
+type selectorMatchTestSuite struct {
+	name     string
+	selector string
+	id       string
+	expected bool
+}
+
+var selectorMatchTestSuites = []selectorMatchTestSuite{
+	{name: "tag", selector: "p", id: "c", expected: true},
+	{name: "id", selector: "#b", id: "b", expected: true},
+	{name: "class", selector: ".cell", id: "a", expected: true},
+	{name: "classes", selector: "p.cell.big", id: "b", expected: true},
+	{name: "missing class", selector: "p.cell.big", id: "a", expected: false},
+	{name: "universal", selector: "*", id: "a", expected: true},
+	{name: "descendant", selector: "table p", id: "b", expected: true},
+	{name: "descendant outside", selector: "table p", id: "c", expected: false},
+	{name: "child", selector: "trow > p", id: "a", expected: true},
+	{name: "child too deep", selector: "trow > p", id: "b", expected: false},
+	{name: "chain", selector: "#tbl > .row div > .cell", id: "b", expected: true},
+	{name: "list", selector: "#x, .odd p", id: "a", expected: true},
+	{name: "focus", selector: "p:focus", id: "a", expected: false},
+}
+
+func TestSelectorMatch(t *testing.T) {
+	for _, suite := range selectorMatchTestSuites {
+		t.Run(suite.name, func(t *testing.T) {
+			sel, err := dom.ParseSelector(suite.selector)
+			assert.Nil(t, err)
+			assert.Equal(t, suite.expected, sel.Match(findByID(selectorTree, suite.id)))
+		})
+	}
+}
+
+func TestSelectorPseudo(t *testing.T) {
+	a := findByID(selectorTree, "a")
+	sel := dom.MustParseSelector(".odd:hover .cell")
+	assert.False(t, sel.Match(a))
+	a.Parent.State.Hovered = true
+	assert.True(t, sel.Match(a))
+	a.Parent.State.Hovered = false
+}
+
+func TestSelectorSpecificity(t *testing.T) {
+	assert.Equal(t, dom.Specificity{1, 2, 1}, dom.MustParseSelector("#tbl .row p:focus")[0].Specificity())
+	assert.Equal(t, dom.Specificity{0, 0, 0}, dom.MustParseSelector("*")[0].Specificity())
+}
+
+func TestSelectorInvalid(t *testing.T) {
+	for _, s := range []string{"", "> p", "p >", "p >> a", "p:active", "p.", "#", "p,"} {
+		_, err := dom.ParseSelector(s)
+		assert.ErrorIs(t, err, dom.ErrInvalidSelector, s)
+	}
+}
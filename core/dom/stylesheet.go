@@ -0,0 +1,139 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrMalformedDeclaration = errors.New("malformed declaration")
+	ErrMalformedRule        = errors.New("malformed rule")
+)
+
+var cssCommentReg = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// --------------------
+//     Stylesheet
+// --------------------
+
+type Rule struct {
+	Selector     Selector
+	Declarations RawAttributeList
+}
+
+type Stylesheet struct {
+	Rules []Rule
+}
+
+// ParseStylesheet parses rules of the form "selector, selector { name: value; }"
+// where names are attribute names and values are written as they would be
+// in an attribute. A value the attribute does not accept is an error.
+func ParseStylesheet(s string) (*Stylesheet, error) {
+	s = cssCommentReg.ReplaceAllString(s, "")
+	sheet := &Stylesheet{}
+	for {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			if strings.TrimSpace(s) != "" {
+				return nil, fmt.Errorf("%w: %q", ErrMalformedRule, strings.TrimSpace(s))
+			}
+			return sheet, nil
+		}
+		close := strings.IndexByte(s[open:], '}')
+		if close < 0 {
+			return nil, fmt.Errorf("%w: missing closing brace after %q", ErrMalformedRule, strings.TrimSpace(s[:open]))
+		}
+		close += open
+
+		selectors, err := ParseSelector(strings.TrimSpace(s[:open]))
+		if err != nil {
+			return nil, err
+		}
+		decls, err := ParseDeclarations(s[open+1 : close])
+		if err != nil {
+			return nil, err
+		}
+		if err := NewAttributes().Parse(decls); err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.TrimSpace(s[:open]), err)
+		}
+		for _, sel := range selectors {
+			sheet.Rules = append(sheet.Rules, Rule{Selector: sel, Declarations: decls})
+		}
+		s = s[close+1:]
+	}
+}
+
+// ParseDeclarations parses a "name: value; name: value" block into raw
+// attributes.
+func ParseDeclarations(s string) (RawAttributeList, error) {
+	var list RawAttributeList
+	for _, decl := range strings.Split(s, ";") {
+		decl = strings.TrimSpace(decl)
+		if decl == "" {
+			continue
+		}
+		colon := strings.IndexByte(decl, ':')
+		if colon < 1 {
+			return nil, fmt.Errorf("%w: %q", ErrMalformedDeclaration, decl)
+		}
+		name := strings.ToLower(strings.TrimSpace(decl[:colon]))
+		value := strings.TrimSpace(decl[colon+1:])
+		if name == "" || value == "" {
+			return nil, fmt.Errorf("%w: %q", ErrMalformedDeclaration, decl)
+		}
+		list = append(list, RawAttribute{name, value})
+	}
+	return list, nil
+}
+
+// --------------------
+//      Cascade
+// --------------------
+
+type matchedRule struct {
+	rule        *Rule
+	specificity Specificity
+	order       int
+}
+
+// matchingRules returns the rules of the sheets that match el, ordered so
+// that later rules take precedence: by specificity, then by source order.
+func matchingRules(sheets []*Stylesheet, el *Element) []matchedRule {
+	var matched []matchedRule
+	order := 0
+	for _, sheet := range sheets {
+		for i := range sheet.Rules {
+			r := &sheet.Rules[i]
+			if r.Selector.Match(el) {
+				matched = append(matched, matchedRule{
+					rule:        r,
+					specificity: r.Selector.Specificity(),
+					order:       order,
+				})
+			}
+			order++
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity.Less(matched[j].specificity)
+		}
+		return matched[i].order < matched[j].order
+	})
+	return matched
+}
+
+// usesPseudo reports whether any rule of the sheets depends on p.
+func usesPseudo(sheets []*Stylesheet, p PseudoClass) bool {
+	for _, sheet := range sheets {
+		for _, r := range sheet.Rules {
+			if (SelectorList{r.Selector}).HasPseudo(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/stretchr/testify/assert"
+)
+
+var styledTemplate = `<html>
+<head>
+	<title>styled</title>
+	<style>
+		p { color: 1; width: 10 }
+		/* ids beat classes beat tags, whatever the order */
+		#name { color: 2 }
+		.label { color: 4; height: 1 }
+		tbody > trow { background-color: 8; }
+		trow p { border: true }
+		trow:focus { background-color: 9 }
+	</style>
+	<link rel="stylesheet" href="theme.css"></link>
+</head>
+<body>
+	<p id="name" class="label">name</p>
+	<p class="label warning">warn</p>
+	<p color="5">inline</p>
+	<tbody>
+		<trow id="row"><p id="cell" width="3">cell</p></trow>
+	</tbody>
+</body>
+</html>`
+
+func TestDocumentStylesheets(t *testing.T) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(styledTemplate), dom.WithBaseDir("testdata"))
+	assert.Nil(t, err)
+	assert.Len(t, doc.Stylesheets, 2)
+
+	body := doc.Body
+	assert.Equal(t, dom.PaletteColor(2), body.Children[0].Attrs.Color)
+	assert.Equal(t, 1, body.Children[0].Attrs.Height)
+	assert.Equal(t, 10, body.Children[0].Attrs.Width)
+	// the linked sheet comes later, so it wins at equal specificity
+	assert.Equal(t, dom.PaletteColor(3), body.Children[1].Attrs.Color)
+	// inline attributes win over every rule
+	assert.Equal(t, dom.PaletteColor(5), body.Children[2].Attrs.Color)
+
+	row := findByID(body, "row")
+	cell := findByID(body, "cell")
+	assert.Equal(t, dom.PaletteColor(8), row.Attrs.BackGroundColor)
+	assert.Equal(t, dom.PaletteColor(8), cell.Attrs.BackGroundColor)
+	assert.Equal(t, true, cell.Attrs.Border)
+	assert.Equal(t, 3, cell.Attrs.Width)
+
+	row.State.Focused = true
+	doc.Restyle(row)
+	assert.Equal(t, dom.PaletteColor(9), row.Attrs.BackGroundColor)
+	assert.Equal(t, dom.PaletteColor(9), cell.Attrs.BackGroundColor)
+	assert.True(t, doc.UsesPseudo(dom.PseudoClass_Focus))
+	assert.False(t, doc.UsesPseudo(dom.PseudoClass_Hover))
+}
+
+func TestRestyleKeepsSetAttributes(t *testing.T) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(styledTemplate), dom.WithBaseDir("testdata"))
+	assert.Nil(t, err)
+	row := findByID(doc.Body, "row")
+	cell := findByID(doc.Body, "cell")
+
+	// attributes set in code survive every restyle
+	assert.Nil(t, cell.SetAttribute("height", "4"))
+	assert.Nil(t, cell.SetAttribute("border", "false"))
+	doc.Restyle(row)
+	assert.Equal(t, 4, cell.Attrs.Height)
+	assert.Equal(t, false, cell.Attrs.Border)
+	doc.Restyle(row)
+	assert.Equal(t, 4, cell.Attrs.Height, "and every one after it")
+
+	// and win over rules that start or stop to apply
+	assert.Nil(t, cell.SetAttribute("background-color", "1"))
+	row.State.Focused = true
+	doc.Restyle(row)
+	assert.Equal(t, dom.PaletteColor(9), row.Attrs.BackGroundColor)
+	assert.Equal(t, dom.PaletteColor(1), cell.Attrs.BackGroundColor)
+	row.State.Focused = false
+	doc.Restyle(row)
+	assert.Equal(t, dom.PaletteColor(1), cell.Attrs.BackGroundColor)
+
+	// as well as over the inline style of the markup
+	p, err := dom.NewElementFromString(`<p style="color: 2">p</p>`)
+	assert.Nil(t, err)
+	assert.Nil(t, p.SetAttribute("color", "6"))
+	doc.Restyle(p)
+	assert.Equal(t, dom.PaletteColor(6), p.Attrs.Color)
+
+	// fields assigned directly are computed afresh
+	cell.Attrs.Width = 7
+	doc.Restyle(cell)
+	assert.Equal(t, 3, cell.Attrs.Width)
+}
+
+func TestParseStylesheetErrors(t *testing.T) {
+	for _, s := range []string{
+		"p { color 3 }",
+		"p { color: 3",
+		"p { color: 3 } stray",
+		"p > { color: 3 }",
+	} {
+		_, err := dom.ParseStylesheet(s)
+		assert.NotNil(t, err, s)
+	}
+
+	_, err := dom.ParseStylesheet("p { width: 3 }\n.bad { color: blurple }")
+	assert.ErrorIs(t, err, dom.ErrInvalidColor)
+	assert.Contains(t, err.Error(), ".bad")
+
+	_, err = dom.NewDocumentFromReader(strings.NewReader(`<html><head>
+		<style>p { background-color: #12 }</style>
+	</head><body></body></html>`))
+	assert.ErrorIs(t, err, dom.ErrInvalidColor)
+}
+
+func TestParseDeclarations(t *testing.T) {
+	decls, err := dom.ParseDeclarations(" color: 3;Background-Color :4 ; ;flex: 2")
+	assert.Nil(t, err)
+	assert.Equal(t, dom.RawAttributeList{{"color", "3"}, {"background-color", "4"}, {"flex", "2"}}, decls)
+}
@@ -2,8 +2,10 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bep/debounce"
@@ -12,33 +14,90 @@ import (
 	"github.com/saman3d/samtui/core/engine/view"
 )
 
-var ids map[string][]*dom.Element
+var (
+	ErrNotAChild = errors.New("reference element is not a child")
+	ErrNoParent  = errors.New("element has no parent")
+	ErrHierarchy = errors.New("element cannot be moved into its own subtree")
+)
 
+// Engine renders a document to a TTY and turns the input of the TTY into
+// events on the document.
+//
+// Events are handled and frames rendered one at a time under the lock of
+// the engine, so that event listeners may change the document freely.
+// Code that reads or changes the document, its elements or the view from
+// any other goroutine, such as a loop reading PollEvent, must do it within
+// Do.
 type Engine struct {
 	DOM     *dom.Document
 	View    View
 	TTY     TTY
 	Layouts map[LayoutType]Layout
 
-	eventch     chan tty.Event
-	renderstack RenderStack
-
+	// mu is held while an event is handled, a frame is rendered, the view
+	// is reloaded and while Do runs.
+	mu           sync.Mutex
+	ttych        chan tty.Event
+	eventch      chan tty.Event
+	renderstack  RenderStack
+	focused      *dom.Element
+	hovered      *dom.Element
+	textareas    *textAreaStore
+	focusStyle   StateStyle
+	invalidStyle StateStyle
+	// front mirrors what the TTY shows so that only changed cells are
+	// written.
+	front         *view.Front
+	frame         []byte
+	redraw        atomic.Bool
+	frameInterval time.Duration
+	stats         stats
+
+	// loops counts the render loop and the event dispatcher started by
+	// Start, which waits for them before it closes the TTY.
+	loops  sync.WaitGroup
 	cancel func()
 	dbnc   func(func())
 }
 
-func NewEngineFromTemplate(tf io.Reader) (*Engine, error) {
+func NewEngineFromTemplate(tf io.Reader, opts ...EngineOpt) (*Engine, error) {
 	dm, err := dom.NewDocumentFromReader(tf)
 	if err != nil {
 		return nil, err
 	}
+	return NewEngine(dm, opts...)
+}
 
-	t, err := tty.NewTTY()
-	if err != nil {
-		return nil, err
+// NewEngine creates an engine rendering dm. Unless a TTY is given with
+// WithTTY, the controlling terminal is opened.
+func NewEngine(dm *dom.Document, opts ...EngineOpt) (*Engine, error) {
+	e := &Engine{
+		DOM:          dm,
+		ttych:        make(chan tty.Event, 10),
+		eventch:      make(chan tty.Event, 10),
+		focusStyle:   DefaultFocusStyle,
+		invalidStyle: DefaultInvalidStyle,
+		dbnc:         debounce.New(time.Millisecond * 100),
+	}
+	WithMaxFPS(DefaultMaxFPS)(e)
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.TTY == nil {
+		mouse := tty.MouseMode_ButtonEvent
+		if dm.UsesPseudo(dom.PseudoClass_Hover) {
+			mouse = tty.MouseMode_AnyEvent
+		}
+
+		t, err := tty.NewTTY(tty.WithMouse(mouse))
+		if err != nil {
+			return nil, err
+		}
+		e.TTY = t
 	}
 
-	width, height, err := t.WindowSize()
+	width, height, err := e.TTY.WindowSize()
 	if err != nil {
 		return nil, err
 	}
@@ -47,120 +106,327 @@ func NewEngineFromTemplate(tf io.Reader) (*Engine, error) {
 
 	dm.Body.Boundry = v.Boundry()
 
-	renderstack := newRenderStack()
-
-	e := &Engine{
-		DOM:  dm,
-		TTY:  t,
-		View: v,
-		Layouts: map[LayoutType]Layout{
-			LayoutType_Flex:     newFlexLayout(v, renderstack),
-			LayoutType_Block:    newBlockLayout(v, renderstack),
-			LayoutType_Absolute: newAbsoluteLayout(v, renderstack),
-		},
-		renderstack: renderstack,
-		eventch:     make(chan tty.Event, 10),
-		dbnc:        debounce.New(time.Millisecond * 100),
+	e.View = v
+	e.front = view.NewFront(width, height)
+	e.renderstack = newRenderStack()
+	e.textareas = newTextAreaStore()
+	e.Layouts = map[LayoutType]Layout{
+		LayoutType_Flex:     newFlexLayout(v, e.renderstack),
+		LayoutType_Block:    newBlockLayout(v, e.renderstack),
+		LayoutType_Absolute: newAbsoluteLayout(v, e.renderstack),
+		LayoutType_Input:    newInputLayout(v, e.renderstack, e.textareas),
 	}
 
-	e.generateIDsMap()
-
 	return e, nil
 }
 
+// --------------------
+//     Engine Opts
+// --------------------
+
+type EngineOpt func(*Engine)
+
+// WithTTY renders to t instead of the controlling terminal.
+func WithTTY(t TTY) EngineOpt {
+	return func(e *Engine) {
+		e.TTY = t
+	}
+}
+
+// DefaultMaxFPS is the frame rate the render loop is capped at unless
+// WithMaxFPS is given.
+const DefaultMaxFPS = 60
+
+// WithMaxFPS caps the render loop at fps frames per second. A value of zero
+// or less removes the cap.
+func WithMaxFPS(fps int) EngineOpt {
+	return func(e *Engine) {
+		e.frameInterval = 0
+		if fps > 0 {
+			e.frameInterval = time.Second / time.Duration(fps)
+		}
+	}
+}
+
 func (e *Engine) reload() {
-	e.TTY.Clear()
 	w, h, _ := e.TTY.WindowSize()
+	// the screen is not cleared: the next frame redraws every cell in one
+	// go
 	e.View.Resize(w, h)
+	e.redraw.Store(true)
 	e.DOM.Body.Boundry = e.View.Boundry()
 	e.renderstack.Push(e.DOM.Body)
 }
 
+// Reload resizes the view to the TTY and renders the whole document again.
+// Calls made in quick succession, e.g. while a window is dragged, are
+// collapsed into one.
 func (e *Engine) Reload() {
-	e.dbnc(e.reload)
+	e.dbnc(e.ReloadNow)
+}
+
+// ReloadNow is Reload without the debounce. It takes the lock of the
+// engine, so it must not be called from an event listener or within Do.
+func (e *Engine) ReloadNow() {
+	e.Do(e.reload)
+}
+
+// Do runs fn while no event is handled and no frame is rendered. Changes
+// fn makes to the document are rendered in the next frame. Event listeners
+// already run this way and must not call Do, nor must fn.
+func (e *Engine) Do(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn()
+}
+
+func (e *Engine) GetElementByID(id string) []Elementor {
+	return e.elementors(e.DOM.GetElementsByID(id))
 }
 
-func (e *Engine) generateIDsMap() {
-	ids = make(map[string][]*dom.Element)
-	e.generateIDsMapRecursive(e.DOM.Body)
+func (e *Engine) GetElementsByClassName(class string) []Elementor {
+	return e.elementors(e.DOM.GetElementsByClassName(class))
 }
 
-func (e *Engine) generateIDsMapRecursive(el *dom.Element) {
-	if el.Attrs.ID != "" {
-		ids[el.Attrs.ID] = append(ids[el.Attrs.ID], el)
+func (e *Engine) GetElementsByTagName(tag string) []Elementor {
+	return e.elementors(e.DOM.GetElementsByTagName(tag))
+}
 
+// QuerySelector returns the first element matching the selector list s, or
+// nil when nothing matches.
+func (e *Engine) QuerySelector(s string) (Elementor, error) {
+	el, err := e.DOM.QuerySelector(s)
+	if err != nil || el == nil {
+		return nil, err
 	}
+	return newElementUpdater(el, e), nil
+}
 
-	for _, child := range el.Children {
-		e.generateIDsMapRecursive(child)
+// QuerySelectorAll returns every element matching the selector list s in
+// document order.
+func (e *Engine) QuerySelectorAll(s string) ([]Elementor, error) {
+	els, err := e.DOM.QuerySelectorAll(s)
+	if err != nil {
+		return nil, err
 	}
+	return e.elementors(els), nil
 }
 
-func (e *Engine) GetElementByID(id string) []Elementor {
-	elem, ok := ids[id]
-	if !ok {
+func (e *Engine) elementors(els []*dom.Element) []Elementor {
+	if len(els) == 0 {
 		return nil
 	}
-
-	elmtrs := make([]Elementor, len(elem))
-	for i := range elem {
-		elmtrs[i] = newElementUpdater(elem[i], e)
+	elmtrs := make([]Elementor, len(els))
+	for i := range els {
+		elmtrs[i] = newElementUpdater(els[i], e)
 	}
-
 	return elmtrs
 }
 
+// Start takes over the terminal, on the alternate screen when the TTY
+// supports it, and renders the document until Exit is called or cp is done.
+// It returns once the TTY is closed, which restores the terminal.
 func (e *Engine) Start(cp context.Context) error {
 	var ctx context.Context
 	ctx, e.cancel = context.WithCancel(cp)
+	defer e.restoreOnPanic()
 
-	e.TTY.Clear()
-
-	go e.TTY.Watch(ctx, e.eventch)
-
-	go e.Render(ctx)
-
-	e.renderstack.Push(e.DOM.Body)
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
+	if at, ok := e.TTY.(AltScreenTTY); ok {
+		at.EnterAltScreen()
 	}
+	e.clear()
+
+	// the TTY is watched until it is closed below rather than until ctx is
+	// done, since watching stops with closing the TTY
+	wctx, unwatch := context.WithCancel(context.Background())
+	defer unwatch()
+	go e.TTY.Watch(wctx, e.ttych)
+
+	e.loops.Add(2)
+	go func() {
+		defer e.loops.Done()
+		e.dispatch(ctx)
+	}()
+	go func() {
+		defer e.loops.Done()
+		e.Render(ctx)
+	}()
+
+	e.Do(func() {
+		e.renderstack.Push(e.DOM.Body)
+		e.mount(e.DOM.Body)
+	})
+
+	<-ctx.Done()
+	// a frame being written when Exit was called is finished before the
+	// terminal is restored
+	e.loops.Wait()
+	e.TTY.Close()
+	return ctx.Err()
 }
 
+// Render renders pending elements until ctx is done. It sleeps while there
+// is nothing to render and flushes at most once per frame, so that updates
+// arriving within a frame are written together.
 func (e *Engine) Render(ctx context.Context) {
-	for range time.Tick(time.Millisecond * 1) {
-		if e.renderstack.Len() == 0 {
-			continue
+	defer e.restoreOnPanic()
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.renderstack.Ready():
 		}
-		var bnd *dom.Boundry
-		for e.renderstack.Len() != 0 {
-			bl := e.renderstack.Pop()
-			if bl == nil {
-				continue
+		if wait := e.frameInterval - time.Since(last); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
 			}
-			e.renderElement(ctx, bl)
-			if bnd == nil {
-				bnd = &dom.Boundry{}
-				*bnd = bl.Boundry
-			} else if !bnd.Circumscribes(bl.Boundry) {
-				*bnd = bnd.Sum(bl.Boundry)
+		}
+		last = time.Now()
+		e.Do(func() {
+			// Exit may have been called while the lock was waited for
+			if ctx.Err() == nil {
+				e.flush(ctx)
 			}
+		})
+	}
+}
+
+// Flush renders every pending element and writes the cells that changed
+// to the TTY right away. Like ReloadNow it takes the lock of the engine.
+func (e *Engine) Flush() {
+	e.Do(func() { e.flush(context.Background()) })
+}
+
+func (e *Engine) flush(ctx context.Context) {
+	if e.renderstack.Len() == 0 {
+		return
+	}
+	for e.renderstack.Len() != 0 {
+		bl := e.renderstack.Pop()
+		if bl == nil {
+			continue
 		}
-		e.renderBoundry(*bnd)
+		e.renderElement(ctx, bl)
+	}
+	if e.redraw.Swap(false) {
+		e.front.Invalidate()
 	}
+	e.stats.frames.Add(1)
+	e.writeRuns(e.front.Diff(e.View))
 }
 
-func (e *Engine) renderBoundry(bndr dom.Boundry) {
-	for i := bndr.FirstY; i < bndr.SecondY; i++ {
-		e.TTY.SetPos(bndr.FirstX, i)
-		for j := bndr.FirstX; j < bndr.SecondX; j++ {
-			e.TTY.Write(e.View.GetCell(j, i).Bytes())
+// writeRuns writes runs to the TTY as a single frame, with one cursor move
+// per run. The style is set in full once and then only changed where it
+// differs from the previous cell, in the colors and attributes the terminal
+// can show. The frame is wrapped in synchronized output when the terminal
+// supports it, so that it is never shown half drawn.
+func (e *Engine) writeRuns(runs []view.Run) {
+	if len(runs) == 0 {
+		return
+	}
+	caps := capabilities(e.TTY)
+	profile := caps.Profile()
+	buf := e.frame[:0]
+	if caps.SynchronizedOutput {
+		buf = append(buf, syncBegin...)
+	}
+	var style *view.Style
+	var cells int
+	for _, run := range runs {
+		buf = append(buf, caps.CursorPosition(run.X, run.Y)...)
+		for _, c := range run.Cells {
+			if style == nil {
+				buf = append(buf, c.Style.StringFor(profile)...)
+				style = &view.Style{}
+			} else {
+				buf = append(buf, c.Style.TransitionFor(*style, profile)...)
+			}
+			*style = c.Style
+			// continuations are empty, the cursor is already past them
+			buf = append(buf, c.Content...)
 		}
+		cells += len(run.Cells)
+	}
+	if caps.SynchronizedOutput {
+		buf = append(buf, syncEnd...)
+	}
+	n, _ := e.TTY.Write(buf)
+	e.frame = buf
+	e.stats.cells.Add(uint64(cells))
+	e.stats.bytes.Add(uint64(n))
+}
+
+const (
+	syncBegin = "\033[?2026h"
+	syncEnd   = "\033[?2026l"
+)
+
+// capabilities returns what the terminal of t supports, or that of a plain
+// xterm when t cannot tell.
+func capabilities(t TTY) tty.Capabilities {
+	if ct, ok := t.(CapableTTY); ok {
+		return ct.Capabilities()
+	}
+	return tty.DefaultCapabilities()
+}
+
+// clear clears the TTY and the record of what it shows.
+func (e *Engine) clear() {
+	e.TTY.Clear()
+	if e.front != nil {
+		e.front.Clear()
+	}
+}
+
+// --------------------
+//       Stats
+// --------------------
+
+// Stats counts the output of the render loop since the engine was created.
+type Stats struct {
+	// Frames is the number of flushes that rendered pending elements.
+	Frames uint64
+	// Cells is the number of cells written to the TTY.
+	Cells uint64
+	// Bytes is the number of bytes written to the TTY for those cells,
+	// escape sequences included.
+	Bytes uint64
+}
+
+type stats struct {
+	frames atomic.Uint64
+	cells  atomic.Uint64
+	bytes  atomic.Uint64
+}
+
+// Stats returns the render counters. It is safe to call while the engine
+// runs.
+func (e *Engine) Stats() Stats {
+	return Stats{
+		Frames: e.stats.frames.Load(),
+		Cells:  e.stats.cells.Load(),
+		Bytes:  e.stats.bytes.Load(),
 	}
 }
 
 func (e *Engine) renderElement(ctx context.Context, el *dom.Element) error {
+	if el.Attrs.Writable {
+		el.State.Invalid = !validInput(el.Attrs.TextType, el.Content)
+	}
+	if attrs := e.stateAttrs(el); attrs != el.Attrs {
+		defer func(attrs *dom.Attributes) { el.Attrs = attrs }(el.Attrs)
+		el.Attrs = attrs
+	}
+
+	if el.Attrs.Writable {
+		return e.Layouts[LayoutType_Input].Layout(ctx, el, el.Boundry)
+	}
+
 	var err error
 	switch el.Attrs.Display {
 	case dom.Display_Flex:
@@ -187,15 +453,72 @@ func (e *Engine) Update(el *dom.Element) {
 	e.renderstack.Push(el)
 }
 
+// restyle recomputes the attributes of el and its descendants from the
+// document's stylesheets and schedules el for rendering.
+func (e *Engine) restyle(el *dom.Element) {
+	e.DOM.Restyle(el)
+	e.renderstack.Push(el)
+}
+
+// ElementorOf wraps el, which must belong to the document of e, so that it
+// can be changed through the engine.
+func (e *Engine) ElementorOf(el *dom.Element) Elementor {
+	return newElementUpdater(el, e)
+}
+
+// ElementAt returns the topmost element painted at the given screen cell,
+// or nil when the cell is outside the view or was never painted. Outside
+// of event listeners it must be called within Do.
+func (e *Engine) ElementAt(x, y int) Elementor {
+	if x < 0 || y < 0 || int64(x) >= e.View.Width() || int64(y) >= e.View.Height() {
+		return nil
+	}
+	el := e.View.GetCell(x, y).Element
+	if el == nil {
+		return nil
+	}
+	return newElementUpdater(el, e)
+}
+
+// PollEvent returns the channel of the events that listeners did not
+// prevent. Events arriving while the channel is full are dropped, so it
+// need not be read by applications that only use listeners.
 func (e *Engine) PollEvent() <-chan tty.Event {
 	return e.eventch
 }
 
+// Exit stops the engine. Start then waits for the render loop and the
+// event dispatcher to stop, so that no frame is written to the restored
+// terminal, and closes the TTY before it returns. Exit does not wait for
+// that itself, as it may be called from an event listener or within Do.
 func (e *Engine) Exit() {
-	e.TTY.Close()
 	e.cancel()
 }
 
+// restoreOnPanic closes the TTY before a panic of the calling goroutine
+// carries on, so that the terminal is usable when the program dies.
+func (e *Engine) restoreOnPanic() {
+	if r := recover(); r != nil {
+		e.TTY.Close()
+		panic(r)
+	}
+}
+
+// suspend stops the process through the TTY, see tty.TTY.Suspend, and
+// redraws the screen once it is continued. It reports false when the TTY
+// cannot suspend. It is called while an event is handled, so the render
+// loop waits for the lock of the engine and writes nothing to the restored
+// terminal; its next frame is the full redraw.
+func (e *Engine) suspend() bool {
+	st, ok := e.TTY.(SuspendTTY)
+	if !ok {
+		return false
+	}
+	st.Suspend()
+	e.reload()
+	return true
+}
+
 type TTY interface {
 	SetPos(x, y int)
 	WindowSize() (int, int, error)
@@ -206,6 +529,23 @@ type TTY interface {
 	Close() error
 }
 
+// CapableTTY is implemented by TTYs that know what the terminal supports,
+// such as its color depth and synchronized output (DEC private mode 2026).
+type CapableTTY interface {
+	Capabilities() tty.Capabilities
+}
+
+// AltScreenTTY is implemented by TTYs that can show the engine on the
+// alternate screen. Closing the TTY is expected to leave it.
+type AltScreenTTY interface {
+	EnterAltScreen()
+}
+
+// SuspendTTY is implemented by TTYs that can stop the process on Ctrl-Z.
+type SuspendTTY interface {
+	Suspend() error
+}
+
 type View interface {
 	Width() int64
 	Height() int64
@@ -213,19 +553,32 @@ type View interface {
 	Boundry() dom.Boundry
 	Flush()
 	ClearBoundry(bndr dom.Boundry)
-	PrintString(x, y, fg, bg int, zindx uint8, s string)
-	PrintRune(x, y, fg, bg int, zindx uint8, r rune)
-	PrintRuneRepeat(x, y, fg, bg, n int, zindx uint8, axis view.AxisMask, r rune)
+	PrintString(x, y int, style view.Style, zindx uint8, el *dom.Element, s string)
+	PrintText(x, y, width int, style view.Style, zindx uint8, el *dom.Element, s string) int
+	PrintRune(x, y int, style view.Style, zindx uint8, el *dom.Element, r rune)
+	PrintRuneRepeat(x, y int, style view.Style, n int, zindx uint8, el *dom.Element, axis view.AxisMask, r rune)
 	Slice(x, y, l int) view.CellList
 	GetCell(x, y int) *view.Cell
 }
 
+// Elementor changes an element through the engine, which keeps its own
+// state and the screen in step. Outside of event listeners its methods must
+// be called within Engine.Do.
 type Elementor interface {
 	Element() *dom.Element
-	AppendChild(*dom.Element)
-	PrependChild(*dom.Element)
+	AppendChild(*dom.Element) error
+	PrependChild(*dom.Element) error
+	InsertBefore(el, ref *dom.Element) error
+	ReplaceWith(*dom.Element) error
+	Clone(deep bool) *dom.Element
 	Remove()
+	RemoveChildren()
+	MoveTo(parent *dom.Element) error
+	SetAttribute(name, value string) error
+	SetContent(string)
 	Update()
+	Value() string
+	AddEventListener(dom.EventType, dom.EventListener, ...dom.ListenerOpt)
 }
 
 type ElementUpdater struct {
@@ -244,65 +597,244 @@ func (eu *ElementUpdater) Element() *dom.Element {
 	return eu.el
 }
 
+// Value returns the text of a writable element. For a textarea the lines
+// are joined with newlines.
+func (eu *ElementUpdater) Value() string {
+	if isTextArea(eu.el) {
+		return eu.eng.textareas.Get(eu.el).Value()
+	}
+	return eu.el.Content
+}
+
 func (eu *ElementUpdater) Update() {
 	eu.eng.renderstack.Push(eu.el)
 }
 
-func (eu *ElementUpdater) AppendChild(el *dom.Element) {
-	el.Parent = eu.el
-	if el.Attrs.ID != "" {
-		if l, ok := ids[el.Attrs.ID]; ok {
-			l = append(l, el)
-		} else {
-			ids[el.Attrs.ID] = make([]*dom.Element, 0)
-			ids[el.Attrs.ID] = append(ids[el.Attrs.ID], el)
-		}
+func (eu *ElementUpdater) AddEventListener(t dom.EventType, handler dom.EventListener, opts ...dom.ListenerOpt) {
+	eu.el.AddEventListener(t, handler, opts...)
+}
+
+// AppendChild adds el after the children of the element. An el that is
+// already in the document is taken off its parent first; one that contains
+// the element cannot be added to it.
+func (eu *ElementUpdater) AppendChild(el *dom.Element) error {
+	return eu.eng.attach(eu.el, el, len(eu.el.Children))
+}
+
+// PrependChild adds el before the children of the element, like
+// AppendChild.
+func (eu *ElementUpdater) PrependChild(el *dom.Element) error {
+	return eu.eng.attach(eu.el, el, 0)
+}
+
+// InsertBefore inserts el among the children of the element right before
+// ref, like AppendChild. A nil ref appends el.
+func (eu *ElementUpdater) InsertBefore(el, ref *dom.Element) error {
+	if ref == nil {
+		return eu.eng.attach(eu.el, el, len(eu.el.Children))
+	}
+	i := childIndex(eu.el, ref)
+	if i < 0 {
+		return ErrNotAChild
+	}
+	return eu.eng.attach(eu.el, el, i)
+}
+
+// ReplaceWith puts el in place of the element, which is removed from the
+// document. An el that is already in the document is taken off its parent
+// first; one that contains the element cannot replace it.
+func (eu *ElementUpdater) ReplaceWith(el *dom.Element) error {
+	parent := eu.el.Parent
+	if parent == nil {
+		return ErrNoParent
+	}
+	if el == eu.el {
+		return nil
+	}
+	if isAncestorOrSelf(el, eu.el) {
+		return ErrHierarchy
+	}
+	i := childIndex(parent, eu.el)
+	eu.eng.detach(eu.el)
+	return eu.eng.attach(parent, el, i)
+}
+
+// Clone returns a detached copy of the element, ready to be inserted
+// elsewhere.
+func (eu *ElementUpdater) Clone(deep bool) *dom.Element {
+	return eu.el.Clone(deep)
+}
+
+func (eu *ElementUpdater) Remove() {
+	eu.eng.detach(eu.el)
+}
+
+func (eu *ElementUpdater) RemoveChildren() {
+	for len(eu.el.Children) != 0 {
+		eu.eng.detach(eu.el.Children[len(eu.el.Children)-1])
 	}
-	eu.el.AppendChild(el)
 	eu.eng.renderstack.Push(eu.el)
 }
 
-func (eu *ElementUpdater) PrependChild(el *dom.Element) {
-	el.Parent = eu.el
-	if el.Attrs.ID != "" {
-		if l, ok := ids[el.Attrs.ID]; ok {
-			l = append(l, el)
-		} else {
-			ids[el.Attrs.ID] = make([]*dom.Element, 0)
-			ids[el.Attrs.ID] = append(ids[el.Attrs.ID], el)
+// MoveTo detaches the element from its parent and appends it to parent.
+// Unlike Remove followed by AppendChild it keeps focus and editing state
+// and fires no mount events.
+func (eu *ElementUpdater) MoveTo(parent *dom.Element) error {
+	if parent == nil {
+		return ErrNoParent
+	}
+	if isAncestorOrSelf(eu.el, parent) {
+		return ErrHierarchy
+	}
+	if old := eu.el.Parent; old != nil {
+		if i := childIndex(old, eu.el); i >= 0 {
+			old.Children = append(old.Children[:i], old.Children[i+1:]...)
 		}
+		eu.eng.View.ClearBoundry(eu.el.Boundry)
+		eu.eng.renderstack.Push(old)
+	}
+	eu.el.Parent = parent
+	parent.Children = append(parent.Children, eu.el)
+	eu.eng.restyle(eu.el)
+	eu.eng.renderstack.Push(parent)
+	return nil
+}
+
+// SetAttribute changes an attribute of the element, restyles its subtree
+// and relayouts as much of the tree as the attribute can affect.
+func (eu *ElementUpdater) SetAttribute(name, value string) error {
+	if err := eu.el.SetAttribute(name, value); err != nil {
+		return err
+	}
+	eu.eng.DOM.Restyle(eu.el)
+	switch dom.AttrName(name) {
+	case dom.AttrName_ID, dom.AttrName_Class, dom.AttrName_Style:
+		eu.eng.DOM.Index(eu.el)
+	}
+	if paintAttrs[dom.AttrName(name)] {
+		eu.eng.renderstack.Push(eu.el)
+	} else {
+		eu.eng.relayout(eu.el)
+	}
+	return nil
+}
+
+// SetContent replaces the text of the element. Sizes do not depend on the
+// content, so only the element itself is redrawn.
+func (eu *ElementUpdater) SetContent(s string) {
+	eu.el.Content = s
+	if eu.el.Attrs.Writable && !isTextArea(eu.el) {
+		eu.el.State.Cursor = len([]rune(s))
 	}
-	eu.el.Children = append([]*dom.Element{el}, eu.el.Children...)
 	eu.eng.renderstack.Push(eu.el)
 }
 
-func (eu *ElementUpdater) Remove() {
-	eu.eng.View.ClearBoundry(eu.el.Boundry)
-	if eu.el.Attrs.ID != "" {
-		delete(ids, eu.el.Attrs.ID)
-	}
-	for i, ch := range eu.el.Parent.Children {
-		if ch == eu.el {
-			eu.el.Parent.Children = append(eu.el.Parent.Children[:i], eu.el.Parent.Children[i+1:]...)
-			eu.eng.renderstack.Push(eu.el.Parent)
-			return
+// --------------------
+//   Tree Mutations
+// --------------------
+
+// paintAttrs only change how an element draws itself and its children, so
+// setting them never moves the element within its parent.
+var paintAttrs = map[dom.AttrName]bool{
+	dom.AttrName_Color:           true,
+	dom.AttrName_BackGroundColor: true,
+	dom.AttrName_Border:          true,
+	dom.AttrName_FlexDirection:   true,
+	dom.AttrName_Focusable:       true,
+	dom.AttrName_FocusGroup:      true,
+	dom.AttrName_TextAlign:       true,
+	dom.AttrName_WhiteSpace:      true,
+	dom.AttrName_TextOverflow:    true,
+	dom.AttrName_VCenter:         true,
+	dom.AttrName_HCenter:         true,
+	dom.AttrName_TextType:        true,
+	dom.AttrName_Writable:        true,
+}
+
+// attach inserts el at position i among the children of parent, then
+// styles, indexes and mounts it. An el that is already in the document is
+// detached first, i counting it among the children of parent if it is one.
+func (e *Engine) attach(parent, el *dom.Element, i int) error {
+	if isAncestorOrSelf(el, parent) {
+		return ErrHierarchy
+	}
+	if el.Parent != nil {
+		if j := childIndex(el.Parent, el); el.Parent == parent && j >= 0 && j < i {
+			i--
 		}
+		e.detach(el)
 	}
+	el.Parent = parent
+	e.DOM.Restyle(el)
+	parent.Children = append(parent.Children, nil)
+	copy(parent.Children[i+1:], parent.Children[i:])
+	parent.Children[i] = el
+	e.DOM.Index(el)
+	e.renderstack.Push(parent)
+	e.mount(el)
+	return nil
+}
+
+// detach takes el and its subtree out of the document, dropping every
+// piece of engine state that refers to them.
+func (e *Engine) detach(el *dom.Element) {
+	e.releaseFocus(el)
+	if isAncestorOrSelf(el, e.hovered) {
+		e.hovered = el.Parent
+	}
+	e.unmount(el)
+	e.textareas.Delete(el)
+	e.View.ClearBoundry(el.Boundry)
+	e.DOM.Unindex(el)
+	if el.Parent == nil {
+		return
+	}
+	if i := childIndex(el.Parent, el); i >= 0 {
+		el.Parent.Children = append(el.Parent.Children[:i], el.Parent.Children[i+1:]...)
+		e.renderstack.Push(el.Parent)
+	}
+	el.Parent = nil
+}
+
+// relayout schedules the parent of el, which decides where el goes, after
+// clearing the area el used to cover.
+func (e *Engine) relayout(el *dom.Element) {
+	if el.Parent == nil {
+		e.renderstack.Push(el)
+		return
+	}
+	e.View.ClearBoundry(el.Boundry)
+	e.renderstack.Push(el.Parent)
+}
+
+func childIndex(parent, el *dom.Element) int {
+	for i, ch := range parent.Children {
+		if ch == el {
+			return i
+		}
+	}
+	return -1
 }
 
 type RenderStack interface {
 	Push(el *dom.Element)
 	Pop() *dom.Element
 	Len() int
+	// Ready receives a value after a Push. A receive that finds the stack
+	// empty again is possible and harmless.
+	Ready() <-chan struct{}
 }
 
 type renderStack struct {
 	mu       sync.Mutex
 	elements []*dom.Element
+	ready    chan struct{}
 }
 
 func newRenderStack() RenderStack {
-	return &renderStack{}
+	return &renderStack{
+		ready: make(chan struct{}, 1),
+	}
 }
 
 func (rs *renderStack) Push(el *dom.Element) {
@@ -318,6 +850,10 @@ func (rs *renderStack) Push(el *dom.Element) {
 	rs.elements = append(rs.elements, el)
 	copy(rs.elements[1:], rs.elements)
 	rs.elements[0] = el
+	select {
+	case rs.ready <- struct{}{}:
+	default:
+	}
 }
 
 func (rs *renderStack) Pop() *dom.Element {
@@ -332,5 +868,11 @@ func (rs *renderStack) Pop() *dom.Element {
 }
 
 func (rs *renderStack) Len() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
 	return len(rs.elements)
 }
+
+func (rs *renderStack) Ready() <-chan struct{} {
+	return rs.ready
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/saman3d/samtui/core/dom"
 	"github.com/saman3d/samtui/core/engine"
 	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
+	"gotest.tools/v3/assert"
 )
 
 var SimpleHtmlTemplate = `<!DOCTYPE html>
@@ -32,44 +37,112 @@ var SimpleHtmlTemplate = `<!DOCTYPE html>
   </body>
 </html>`
 
-var eng *engine.Engine
-
-func TestEngineSimple(t *testing.T) {
-	var err error
+func newEngine(t *testing.T, vt *tty.VirtualTTY) *engine.Engine {
+	t.Helper()
 	f, err := os.Open("template_test.html")
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer f.Close()
 
-	eng, err = engine.NewEngineFromTemplate(f)
+	eng, err := engine.NewEngineFromTemplate(f, engine.WithTTY(vt))
 	if err != nil {
 		t.Fatal(err)
 	}
+	return eng
+}
 
-	go mm()
+func startEngine(eng *engine.Engine) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- eng.Start(context.Background()) }()
+	return done
+}
 
-	eng.Start(context.Background())
+func waitForText(t *testing.T, vt *tty.VirtualTTY, s string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(vt.Text(), s) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%q never appeared on screen:\n%s", s, vt.Text())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
 }
 
-func mm() {
-	element := eng.GetElementByID("table_body")
+// waitForFrames waits until the engine has rendered n frames.
+func waitForFrames(t *testing.T, eng *engine.Engine, n uint64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for eng.Stats().Frames < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d frames were rendered", eng.Stats().Frames, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEngineSimple(t *testing.T) {
+	vt := tty.NewVirtualTTY(120, 40)
+	eng := newEngine(t, vt)
 
+	element := eng.GetElementByID("table_body")
 	for i := 0; i < 100; i++ {
 		elem, _ := dom.NewElementFromString(fmt.Sprintf(`<div height="1" display="flex">
 						<p id="pid" width="10">PID</p>
 						<p id="cpu" width="10">CPU</p>
 						<p id="mem" width="10">MEM</p>
-						<p id="name">%d</p>
+						<p id="name">row-%d</p>
 		</div>`, i))
-		element[0].Element().Children = append(element[0].Element().Children, elem)
-		// element[0].AppendChild(elem)
+		element[0].AppendChild(elem)
+	}
+	done := startEngine(eng)
+	waitForText(t, vt, "row-0")
+
+	go mm(eng, element[0])
+	vt.SendKey(tty.KeyboardEvent{Key: tty.SpecialKey_Down})
+	vt.SendKey(tty.KeyboardEvent{Key: tty.NewByteKey('q')})
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("engine did not exit")
+	}
+	assert.Assert(t, vt.Closed())
+	assert.Equal(t, dom.PaletteColor(23), element[0].Element().Children[1].Attrs.BackGroundColor)
+}
+
+func TestEngineResize(t *testing.T) {
+	vt := tty.NewVirtualTTY(80, 24)
+	eng := newEngine(t, vt)
+	done := startEngine(eng)
+	waitForText(t, vt, "PID")
+
+	go func() {
+		for ev := range eng.PollEvent() {
+			if ev.Type() == tty.EventType_Resize {
+				eng.Reload()
+			}
+		}
+	}()
+	vt.Resize(100, 30)
+	deadline := time.Now().Add(2 * time.Second)
+	for width := int64(0); width != 100; {
+		eng.Do(func() { width = eng.View.Width() })
+		if time.Now().After(deadline) {
+			t.Fatal("view was not resized")
+		}
+		time.Sleep(5 * time.Millisecond)
 	}
-	element[0].Update()
+	waitForText(t, vt, "PID")
+	eng.Exit()
+	<-done
+}
+
+func mm(eng *engine.Engine, element engine.Elementor) {
 	var i int
-	element[0].Element().Children[i].Attrs.BackGroundColor = 23
-	element[0].Element().Children[i].Attrs.Color = 3
-	element[0].Element().Children[i].InheritChildrensAttr()
-	element[0].Update()
+	rows := element.Element().Children
+	eng.ElementorOf(rows[i]).SetAttribute("style", "background-color: 23; color: 3")
 	for ev := range eng.PollEvent() {
 		switch ev.Type() {
 		case tty.EventType_Keyboard:
@@ -77,34 +150,286 @@ func mm() {
 			switch {
 			case ev.Is(tty.NewByteKey('c'), tty.Modifier_Ctrl):
 				eng.Exit()
+				return
 			case ev.Is(tty.NewByteKey('q')):
 				eng.Exit()
+				return
 			case ev.Is(tty.SpecialKey_Down, tty.Modifier_None):
+				eng.ElementorOf(rows[i]).SetAttribute("style", "background-color: 0; color: 0")
 				i++
-				element[0].Element().Children[i].Attrs.BackGroundColor = 23
-				element[0].Element().Children[i].Attrs.Color = 3
-				element[0].Element().Children[i].InheritChildrensAttr()
-				element[0].Element().Children[i-1].Content = fmt.Sprintf("%d", 0)
-				element[0].Element().Children[i-1].Attrs.BackGroundColor = 0
-				element[0].Element().Children[i-1].Attrs.Color = 0
-				element[0].Element().Children[i-1].InheritChildrensAttr()
-				eng.Update(element[0].Element().Children[i])
-				eng.Update(element[0].Element().Children[i-1])
+				eng.ElementorOf(rows[i]).SetAttribute("style", "background-color: 23; color: 3")
 			case ev.Is(tty.SpecialKey_Up, tty.Modifier_None):
+				eng.ElementorOf(rows[i]).SetAttribute("style", "background-color: 0; color: 0")
 				i--
-				element[0].Element().Children[i].Content = fmt.Sprintf("%d", 23)
-				element[0].Element().Children[i].Attrs.BackGroundColor = 23
-				element[0].Element().Children[i].Attrs.Color = 3
-				element[0].Element().Children[i].InheritChildrensAttr()
-				element[0].Element().Children[i+1].Content = fmt.Sprintf("%d", 0)
-				element[0].Element().Children[i+1].Attrs.BackGroundColor = 0
-				element[0].Element().Children[i+1].Attrs.Color = 0
-				element[0].Element().Children[i+1].InheritChildrensAttr()
-				eng.Update(element[0].Element().Children[i])
-				eng.Update(element[0].Element().Children[i+1])
+				eng.ElementorOf(rows[i]).SetAttribute("style", "background-color: 23; color: 3")
 			}
+		}
+	}
+}
+
+func TestEngineDiffRender(t *testing.T) {
+	vt := tty.NewVirtualTTY(40, 10)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex" flex-direction="column">
+		<p id="a" height="1">hello</p>
+		<p id="b" height="1">world</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	assert.NilError(t, err)
+
+	eng.Update(doc.Body)
+	eng.Flush()
+	assert.Assert(t, strings.Contains(vt.Text(), "hello\nworld"))
 
+	vt.ResetOutput()
+	eng.Update(doc.Body)
+	eng.Flush()
+	assert.Equal(t, string(vt.Output()), "", "an unchanged frame writes nothing")
+
+	before := eng.Stats()
+	eng.GetElementByID("b")[0].SetContent("wormd")
+	eng.Flush()
+	assert.Equal(t, string(vt.Output()), "\033[?2026h\033[2;4H\033[0mm\033[?2026l")
+	assert.Equal(t, vt.Writes(), 1)
+	after := eng.Stats()
+	assert.Equal(t, after.Frames-before.Frames, uint64(1))
+	assert.Equal(t, after.Cells-before.Cells, uint64(1))
+	assert.Equal(t, after.Bytes-before.Bytes, uint64(len(vt.Output())))
+	assert.Assert(t, strings.Contains(vt.Text(), "hello\nwormd"))
+}
+
+func TestEngineUnreadEvents(t *testing.T) {
+	vt := tty.NewVirtualTTY(40, 10)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex">
+		<p height="1">hello</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	assert.NilError(t, err)
+	var keys atomic.Int32
+	doc.Body.AddEventListener(dom.EventType_Key, func(ev *dom.Event) { keys.Add(1) })
+
+	done := startEngine(eng)
+	waitForText(t, vt, "hello")
+
+	// nothing reads PollEvent, yet the events that would be forwarded to it
+	// do not hold up the ones after them
+	for i := 0; i < 30; i++ {
+		vt.SendKey(tty.KeyboardEvent{Key: tty.NewByteKey('x')})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for keys.Load() != 30 {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of 30 keys reached the listener", keys.Load())
 		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	eng.Exit()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestEngineExitFromListener(t *testing.T) {
+	vt := tty.NewVirtualTTY(40, 10)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex">
+		<p height="1">hello</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	assert.NilError(t, err)
+	doc.Body.AddEventListener(dom.EventType_Key, func(ev *dom.Event) { eng.Exit() })
+
+	done := startEngine(eng)
+	waitForText(t, vt, "hello")
+	vt.SendKey(tty.KeyboardEvent{Key: tty.NewByteKey('q')})
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("engine did not exit")
+	}
+	assert.Assert(t, vt.Closed(), "the TTY is closed once Start returns")
+}
+
+func TestEngineRenderLoop(t *testing.T) {
+	vt := tty.NewVirtualTTY(40, 10)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex" flex-direction="column">
+		<p id="a" height="1">hello</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt), engine.WithMaxFPS(10))
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		eng.Render(ctx)
+		close(done)
+	}()
 
+	eng.Do(func() { eng.Update(doc.Body) })
+	waitForFrames(t, eng, 1)
+	waitForText(t, vt, "hello")
+	writes := vt.Writes()
+
+	// the updates are made under the lock of the engine, so the loop sees
+	// them all at once
+	eng.Do(func() {
+		p := eng.GetElementByID("a")[0]
+		for _, s := range []string{"one", "two", "three"} {
+			p.SetContent(s)
+		}
+	})
+	waitForFrames(t, eng, 2)
+	waitForText(t, vt, "three")
+	assert.Equal(t, vt.Writes(), writes+1, "updates within a frame are batched")
+	assert.Equal(t, eng.Stats().Frames, uint64(2), "an idle loop renders nothing")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("render loop did not stop on cancel")
 	}
 }
+
+func TestEngineResizeFrame(t *testing.T) {
+	for _, synced := range []bool{true, false} {
+		t.Run(fmt.Sprint("synchronized=", synced), func(t *testing.T) {
+			vt := tty.NewVirtualTTY(20, 4)
+			vt.SetSynchronizedOutput(synced)
+			doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex">
+				<p height="1">left</p>
+				<p height="1">right</p>
+			</body></html>`))
+			assert.NilError(t, err)
+			eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+			assert.NilError(t, err)
+			eng.Update(doc.Body)
+			eng.Flush()
+
+			vt.ResetOutput()
+			vt.SetSize(30, 5)
+			eng.ReloadNow()
+			eng.Flush()
+
+			// the relayout reaches the terminal as one frame
+			assert.Equal(t, vt.Writes(), 1)
+			out := string(vt.Output())
+			assert.Equal(t, strings.HasPrefix(out, "\033[?2026h"), synced)
+			assert.Equal(t, strings.HasSuffix(out, "\033[?2026l"), synced)
+			assert.Equal(t, strings.Count(out, "\033[2J"), 0)
+			assert.Equal(t, vt.Screen()[0], "left           right          ")
+		})
+	}
+}
+
+func TestEngineColorDepth(t *testing.T) {
+	for _, tc := range []struct {
+		depth view.ColorDepth
+		want  string
+	}{
+		{view.ColorDepth_TrueColor, "\033[0;38;2;255;135;0;48;5;4morange"},
+		{view.ColorDepth_256, "\033[0;38;5;208;48;5;4morange"},
+		{view.ColorDepth_16, "\033[0;33;44morange"},
+		{view.ColorDepth_None, "\033[0morange"},
+	} {
+		t.Run(fmt.Sprint("colors=", tc.depth), func(t *testing.T) {
+			vt := tty.NewVirtualTTY(10, 1)
+			caps := tty.DefaultCapabilities()
+			caps.Colors = tc.depth
+			vt.SetCapabilities(caps)
+			doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex">
+				<p height="1" color="#ff8700" background-color="blue">orange</p>
+			</body></html>`))
+			assert.NilError(t, err)
+			eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+			assert.NilError(t, err)
+			eng.Update(doc.Body)
+			eng.Flush()
+
+			assert.Assert(t, strings.Contains(string(vt.Output()), tc.want), "%q", vt.Output())
+		})
+	}
+}
+
+func TestEngineTextAttributes(t *testing.T) {
+	vt := tty.NewVirtualTTY(20, 2)
+	caps := tty.DefaultCapabilities()
+	caps.Undercurl = true
+	vt.SetCapabilities(caps)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex" flex-direction="column">
+		<p height="1" font-weight="bold" style="text-decoration: underline wavy red">loud</p>
+		<p height="1" reverse="true" font-style="italic">quiet</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	assert.NilError(t, err)
+	eng.Update(doc.Body)
+	eng.Flush()
+
+	loud := vt.CellAt(0, 0)
+	assert.Equal(t, loud.Content, "l")
+	assert.Equal(t, loud.Style, view.Style{
+		Attrs:          view.Attr_Bold | view.Attr_Underline,
+		UnderlineStyle: dom.TextDecorationStyle_Wavy,
+		UnderlineColor: dom.PaletteColor(1),
+	})
+	// the decoration is drawn under the text only
+	assert.Equal(t, vt.CellAt(10, 0).Style, view.Style{})
+
+	quiet := vt.CellAt(0, 1)
+	assert.Equal(t, quiet.Content, "q")
+	assert.Equal(t, quiet.Style, view.Style{Attrs: view.Attr_Reverse | view.Attr_Italic})
+	assert.Equal(t, vt.CellAt(10, 1).Style, view.Style{Attrs: view.Attr_Reverse})
+}
+
+func TestEngineWideText(t *testing.T) {
+	vt := tty.NewVirtualTTY(8, 2)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex">
+		<p width="5" height="2">ab漢字ok</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	assert.NilError(t, err)
+	eng.Update(doc.Body)
+	eng.Flush()
+
+	// a wide character that does not fit at the end of a row goes to the
+	// next one
+	assert.DeepEqual(t, vt.Screen(), []string{"ab漢    ", "字ok    "})
+}
+
+func TestEngineTerminalState(t *testing.T) {
+	vt := tty.NewVirtualTTY(40, 10)
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(`<html><body display="flex">
+		<p height="1">hello</p>
+	</body></html>`))
+	assert.NilError(t, err)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	assert.NilError(t, err)
+
+	done := startEngine(eng)
+	waitForText(t, vt, "hello")
+	assert.Assert(t, vt.Mode("?1049"), "the engine runs on the alternate screen")
+
+	// Ctrl-Z suspends and the whole screen is drawn again on resume
+	vt.ResetOutput()
+	vt.SendBytes([]byte{0x1a})
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(string(vt.Output()), "hello") {
+		if time.Now().After(deadline) {
+			t.Fatalf("screen was not redrawn after resume: %q", vt.Output())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, vt.Suspends(), 1)
+	assert.Assert(t, vt.Mode("?1049"))
+	assert.Assert(t, strings.Contains(string(vt.Output()), "\x1b[?1049l\x1b[?1049h"),
+		"nothing is written between leaving and entering the alternate screen")
+
+	eng.Exit()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Assert(t, !vt.Mode("?1049"), "the main screen is restored")
+	assert.Assert(t, vt.Mode("?25"), "the cursor is shown again")
+}
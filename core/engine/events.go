@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+)
+
+// --------------------
+//     Click Event
+// --------------------
+
+// ClickEvent is delivered through PollEvent in place of the raw
+// tty.MouseEvent when a button press lands on a painted element.
+type ClickEvent struct {
+	tty.MouseEvent
+	// Target is the topmost element under the pointer.
+	Target Elementor
+}
+
+// --------------------
+//   Event Dispatcher
+// --------------------
+
+func (e *Engine) dispatch(ctx context.Context) {
+	defer e.restoreOnPanic()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-e.ttych:
+			// the lock is released before the event is delivered, since
+			// its reader may well call Do
+			var out tty.Event
+			var ok bool
+			e.Do(func() { out, ok = e.handleEvent(ev) })
+			if ok {
+				e.forward(out)
+			}
+		}
+	}
+}
+
+// forward delivers ev through PollEvent. The event is dropped when the
+// channel is full, so that an application that never reads PollEvent and
+// relies on listeners alone does not stall the handling of input.
+func (e *Engine) forward(ev tty.Event) {
+	select {
+	case e.eventch <- ev:
+	default:
+	}
+}
+
+// HandleEvent processes ev synchronously, as if it had been read from the
+// TTY. It returns the event that would be delivered through PollEvent and
+// whether it would be delivered at all. Like Do it takes the lock of the
+// engine.
+func (e *Engine) HandleEvent(ev tty.Event) (out tty.Event, ok bool) {
+	e.Do(func() { out, ok = e.handleEvent(ev) })
+	return out, ok
+}
+
+// handleEvent turns a tty event into a DOM event and dispatches it. It
+// returns the event to forward through PollEvent, and false when a
+// listener prevented the default action or the engine consumed the event.
+func (e *Engine) handleEvent(ev tty.Event) (tty.Event, bool) {
+	switch ev := ev.(type) {
+	case tty.KeyboardEvent:
+		if !e.dispatchEvent(e.keyTarget(), dom.EventType_Key, ev) {
+			return ev, false
+		}
+		if ev.Action == tty.KeyAction_Release {
+			// releases reach listeners but neither edit nor move focus
+			return ev, true
+		}
+		// Ctrl-Z suspends even while an editor holds the focus
+		if ev.Is(tty.NewByteKey('z'), tty.Modifier_Ctrl) && e.suspend() {
+			return ev, false
+		}
+		if e.handleWritableKey(ev) {
+			return ev, false
+		}
+		return ev, !e.handleFocusKey(ev)
+	case tty.PasteEvent:
+		if !e.dispatchEvent(e.keyTarget(), dom.EventType_Paste, ev) {
+			return ev, false
+		}
+		return ev, !e.handleWritablePaste(ev.Text)
+	case tty.MouseEvent:
+		return e.handleMouseEvent(ev)
+	case tty.ResizeEvent:
+		return ev, e.dispatchEvent(e.DOM.Body, dom.EventType_Resize, ev)
+	}
+	return ev, true
+}
+
+func (e *Engine) handleMouseEvent(me tty.MouseEvent) (tty.Event, bool) {
+	target := e.ElementAt(me.X, me.Y)
+	if target == nil {
+		e.hover(nil)
+		return me, true
+	}
+	e.hover(target.Element())
+
+	switch {
+	case isClick(me):
+		ok := e.dispatchEvent(target.Element(), dom.EventType_Click, me)
+		if ok {
+			e.focusWithin(target.Element())
+			if el := target.Element(); el == e.focused && el.Attrs.Writable {
+				e.placeCursor(el, me.X, me.Y)
+			}
+		}
+		return ClickEvent{
+			MouseEvent: me,
+			Target:     target,
+		}, ok
+	case me.Button.IsWheel():
+		return me, e.dispatchEvent(target.Element(), dom.EventType_Scroll, me)
+	}
+	return me, true
+}
+
+// hover marks el and its ancestors as hovered and clears the elements the
+// pointer left. Changed elements are restyled when the stylesheets have
+// :hover rules.
+func (e *Engine) hover(el *dom.Element) {
+	if el == e.hovered {
+		return
+	}
+
+	var changed []*dom.Element
+	for p := e.hovered; p != nil; p = p.Parent {
+		if !isAncestorOrSelf(p, el) {
+			p.State.Hovered = false
+			changed = append(changed, p)
+		}
+	}
+	for p := el; p != nil; p = p.Parent {
+		if !p.State.Hovered {
+			p.State.Hovered = true
+			changed = append(changed, p)
+		}
+	}
+	e.hovered = el
+
+	if !e.DOM.UsesPseudo(dom.PseudoClass_Hover) {
+		return
+	}
+	for _, c := range changed {
+		e.restyle(c)
+	}
+}
+
+// isAncestorOrSelf reports whether a is el or one of its ancestors.
+func isAncestorOrSelf(a, el *dom.Element) bool {
+	for ; el != nil; el = el.Parent {
+		if el == a {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWritableKey hands ev to the editor of the focused element and
+// reports whether it was consumed.
+func (e *Engine) handleWritableKey(ev tty.KeyboardEvent) bool {
+	el := e.focused
+	switch {
+	case el == nil || !el.Attrs.Writable:
+		return false
+	case isTextArea(el):
+		return e.handleTextAreaKey(el, ev)
+	default:
+		return e.handleInputKey(el, ev)
+	}
+}
+
+// handleWritablePaste inserts text into the focused writable element and
+// reports whether it did.
+func (e *Engine) handleWritablePaste(text string) bool {
+	el := e.focused
+	switch {
+	case el == nil || !el.Attrs.Writable:
+		return false
+	case isTextArea(el):
+		e.handleTextAreaPaste(el, text)
+	default:
+		e.handleInputPaste(el, text)
+	}
+	return true
+}
+
+// placeCursor moves the caret of the writable element el under the screen
+// cell x, y.
+func (e *Engine) placeCursor(el *dom.Element, x, y int) {
+	if isTextArea(el) {
+		e.placeTextAreaCursor(el, x, y)
+	} else {
+		placeInputCursor(el, x)
+	}
+	e.renderstack.Push(el)
+}
+
+// keyTarget returns the element keyboard events are dispatched to: the
+// focused element, or the body when nothing holds the focus.
+func (e *Engine) keyTarget() *dom.Element {
+	if e.focused != nil {
+		return e.focused
+	}
+	return e.DOM.Body
+}
+
+func (e *Engine) dispatchEvent(target *dom.Element, t dom.EventType, detail interface{}) bool {
+	return target.DispatchEvent(dom.NewEvent(t, detail))
+}
+
+// mount fires a mount event on el and every descendant, parents first.
+func (e *Engine) mount(el *dom.Element) {
+	el.Walk(func(el *dom.Element) {
+		e.dispatchEvent(el, dom.EventType_Mount, nil)
+	})
+}
+
+// unmount fires an unmount event on el and every descendant, parents first.
+func (e *Engine) unmount(el *dom.Element) {
+	el.Walk(func(el *dom.Element) {
+		e.dispatchEvent(el, dom.EventType_Unmount, nil)
+	})
+}
+
+func isClick(me tty.MouseEvent) bool {
+	if me.Action != tty.MouseAction_Press {
+		return false
+	}
+	switch me.Button {
+	case tty.MouseButton_Left, tty.MouseButton_Right, tty.MouseButton_Middle:
+		return true
+	}
+	return false
+}
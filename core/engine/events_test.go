@@ -0,0 +1,324 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
+	"gotest.tools/v3/assert"
+)
+
+var hitTestTemplate = `<html>
+<head></head>
+<body display="flex" flex-direction="column">
+	<div id="top" height="2"></div>
+	<div id="bottom" display="flex">
+		<p id="left">left</p>
+		<p id="right">right</p>
+	</div>
+	<div id="modal" display="absolute" z-index="2" left="3" top="1" width="4" height="2"></div>
+</body>
+</html>`
+
+func newRenderedEngine(t *testing.T, template string, width, height int) *Engine {
+	t.Helper()
+	dm, err := dom.NewDocumentFromReader(strings.NewReader(template))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := view.NewView(int64(width), int64(height))
+	dm.Body.Boundry = v.Boundry()
+	rs := newRenderStack()
+	tas := newTextAreaStore()
+	e := &Engine{
+		DOM:  dm,
+		View: v,
+		Layouts: map[LayoutType]Layout{
+			LayoutType_Flex:     newFlexLayout(v, rs),
+			LayoutType_Block:    newBlockLayout(v, rs),
+			LayoutType_Absolute: newAbsoluteLayout(v, rs),
+			LayoutType_Input:    newInputLayout(v, rs, tas),
+		},
+		textareas:    tas,
+		renderstack:  rs,
+		focusStyle:   DefaultFocusStyle,
+		invalidStyle: DefaultInvalidStyle,
+	}
+
+	rs.Push(dm.Body)
+	e.flushRenderStack()
+	return e
+}
+
+func (e *Engine) flushRenderStack() {
+	for e.renderstack.Len() != 0 {
+		e.renderElement(context.Background(), e.renderstack.Pop())
+	}
+}
+
+type elementAtTestSuite struct {
+	name     string
+	x, y     int
+	expected string
+}
+
+var elementAtTestSuites = []elementAtTestSuite{
+	{name: "top", x: 0, y: 0, expected: "top"},
+	{name: "left", x: 1, y: 4, expected: "left"},
+	{name: "right", x: 8, y: 4, expected: "right"},
+	{name: "modal above top", x: 4, y: 1, expected: "modal"},
+	{name: "modal above left", x: 3, y: 2, expected: "modal"},
+	{name: "outside", x: 10, y: 6, expected: ""},
+}
+
+func TestElementAt(t *testing.T) {
+	e := newRenderedEngine(t, hitTestTemplate, 10, 6)
+	for _, suite := range elementAtTestSuites {
+		t.Run(suite.name, func(t *testing.T) {
+			el := e.ElementAt(suite.x, suite.y)
+			if suite.expected == "" {
+				assert.Assert(t, el == nil)
+				return
+			}
+			assert.Assert(t, el != nil)
+			assert.Equal(t, suite.expected, el.Element().Attrs.ID)
+		})
+	}
+}
+
+func TestHandleClick(t *testing.T) {
+	e := newRenderedEngine(t, hitTestTemplate, 10, 6)
+
+	ev, ok := e.handleEvent(tty.MouseEvent{X: 8, Y: 4, Button: tty.MouseButton_Left})
+	assert.Assert(t, ok)
+	click, isClick := ev.(ClickEvent)
+	assert.Assert(t, isClick)
+	assert.Equal(t, "right", click.Target.Element().Attrs.ID)
+
+	wheel := tty.MouseEvent{X: 8, Y: 4, Button: tty.MouseButton_WheelDown}
+	ev, ok = e.handleEvent(wheel)
+	assert.Assert(t, ok)
+	assert.Equal(t, wheel, ev)
+}
+
+func TestHandleEventListeners(t *testing.T) {
+	e := newRenderedEngine(t, hitTestTemplate, 10, 6)
+	bottom := e.GetElementByID("bottom")[0]
+
+	var log []dom.EventType
+	bottom.AddEventListener(dom.EventType_Click, func(ev *dom.Event) {
+		log = append(log, ev.Type)
+		assert.Equal(t, "left", ev.Target.Attrs.ID)
+		ev.PreventDefault()
+	})
+	bottom.AddEventListener(dom.EventType_Scroll, func(ev *dom.Event) {
+		log = append(log, ev.Type)
+	})
+	e.DOM.Body.AddEventListener(dom.EventType_Key, func(ev *dom.Event) {
+		log = append(log, ev.Type)
+	})
+
+	_, ok := e.handleEvent(tty.MouseEvent{X: 1, Y: 4, Button: tty.MouseButton_Left})
+	assert.Assert(t, !ok)
+	_, ok = e.handleEvent(tty.MouseEvent{X: 1, Y: 4, Button: tty.MouseButton_WheelUp})
+	assert.Assert(t, ok)
+	_, ok = e.handleEvent(tty.KeyboardEvent{Key: tty.NewByteKey('a')})
+	assert.Assert(t, ok)
+
+	assert.DeepEqual(t, []dom.EventType{dom.EventType_Click, dom.EventType_Scroll, dom.EventType_Key}, log)
+}
+
+var pseudoTemplate = `<html>
+<head>
+	<style>
+		.item:hover { background-color: 4 }
+		.item:focus { color: 2 }
+	</style>
+</head>
+<body display="flex" flex-direction="column">
+	<p id="one" class="item" height="1" focusable="true">one</p>
+	<p id="two" class="item" height="1" focusable="true">two</p>
+</body>
+</html>`
+
+func TestPseudoClassRestyle(t *testing.T) {
+	e := newRenderedEngine(t, pseudoTemplate, 6, 2)
+	one := e.GetElementByID("one")[0].Element()
+	two := e.GetElementByID("two")[0].Element()
+
+	e.handleEvent(tty.MouseEvent{X: 1, Y: 0, Action: tty.MouseAction_Motion})
+	assert.Equal(t, dom.PaletteColor(4), one.Attrs.BackGroundColor)
+	e.handleEvent(tty.MouseEvent{X: 1, Y: 1, Action: tty.MouseAction_Motion})
+	assert.Equal(t, dom.ColorDefault, one.Attrs.BackGroundColor)
+	assert.Equal(t, dom.PaletteColor(4), two.Attrs.BackGroundColor)
+
+	e.Focus(one)
+	assert.Equal(t, dom.PaletteColor(2), one.Attrs.Color)
+	e.Focus(two)
+	assert.Equal(t, dom.ColorDefault, one.Attrs.Color)
+	assert.Equal(t, dom.PaletteColor(2), two.Attrs.Color)
+}
+
+func TestQueryAfterMutation(t *testing.T) {
+	e := newRenderedEngine(t, hitTestTemplate, 10, 6)
+
+	found, err := e.QuerySelectorAll("#bottom > p")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(found))
+
+	bottom := e.GetElementByID("bottom")[0]
+	bottom.AppendChild(dom.MustParseElementFromString(`<p id="left" class="extra">again</p>`))
+	assert.Equal(t, 2, len(e.GetElementByID("left")))
+	assert.Equal(t, 1, len(e.GetElementsByClassName("extra")))
+
+	e.GetElementByID("left")[0].Remove()
+	left := e.GetElementByID("left")
+	assert.Equal(t, 1, len(left))
+	assert.Equal(t, "again", left[0].Element().Content)
+
+	bottom.Remove()
+	assert.Assert(t, e.GetElementByID("left") == nil)
+	assert.Assert(t, e.GetElementsByClassName("extra") == nil)
+	el, err := e.QuerySelector("#right")
+	assert.NilError(t, err)
+	assert.Assert(t, el == nil)
+	assert.Equal(t, 1, len(e.GetElementsByTagName("body")))
+}
+
+func TestElementMutations(t *testing.T) {
+	e := newRenderedEngine(t, hitTestTemplate, 10, 6)
+	bottom := e.GetElementByID("bottom")[0]
+	left := e.GetElementByID("left")[0].Element()
+	right := e.GetElementByID("right")[0].Element()
+
+	var mounted []string
+	e.DOM.Body.AddEventListener(dom.EventType_Mount, func(ev *dom.Event) {
+		mounted = append(mounted, ev.Target.Attrs.ID)
+	}, dom.WithCapture())
+
+	mid := dom.MustParseElementFromString(`<p id="mid">mid</p>`)
+	assert.NilError(t, bottom.InsertBefore(mid, right))
+	assertChildren(t, bottom.Element(), left, mid, right)
+	assert.ErrorIs(t, bottom.InsertBefore(dom.NewElement("p"), e.DOM.Body), ErrNotAChild)
+
+	clone := e.GetElementByID("mid")[0].Clone(true)
+	assert.NilError(t, e.ElementorOf(left).ReplaceWith(clone))
+	assertChildren(t, bottom.Element(), clone, mid, right)
+	assert.Equal(t, 2, len(e.GetElementByID("mid")))
+	assert.Assert(t, e.GetElementByID("left") == nil)
+	assert.DeepEqual(t, []string{"mid", "mid"}, mounted)
+
+	top := e.GetElementByID("top")[0].Element()
+	assert.NilError(t, e.ElementorOf(right).MoveTo(top))
+	assert.Equal(t, top, right.Parent)
+	assert.Equal(t, 2, len(bottom.Element().Children))
+	assert.ErrorIs(t, bottom.MoveTo(mid), ErrHierarchy)
+	assert.ErrorIs(t, bottom.MoveTo(nil), ErrNoParent)
+
+	// an element of the document leaves its old place to replace another
+	assert.NilError(t, e.ElementorOf(clone).ReplaceWith(mid))
+	assertChildren(t, bottom.Element(), mid)
+	assert.Equal(t, 1, len(e.GetElementByID("mid")))
+	assert.ErrorIs(t, e.ElementorOf(mid).ReplaceWith(bottom.Element()), ErrHierarchy)
+
+	// appending an element of the document moves it, appending an
+	// ancestor is refused
+	assert.NilError(t, e.ElementorOf(top).AppendChild(mid))
+	assertChildren(t, top, right, mid)
+	assert.Equal(t, 0, len(bottom.Element().Children))
+	assert.Equal(t, 1, len(e.GetElementByID("mid")))
+	assert.NilError(t, e.ElementorOf(top).InsertBefore(mid, right))
+	assertChildren(t, top, mid, right)
+	assert.ErrorIs(t, e.ElementorOf(mid).AppendChild(top), ErrHierarchy)
+	assert.ErrorIs(t, e.ElementorOf(mid).PrependChild(mid), ErrHierarchy)
+	assert.NilError(t, bottom.AppendChild(mid))
+	assertChildren(t, bottom.Element(), mid)
+
+	// an id given in code is kept when the element is styled and indexed
+	coded := dom.NewElement("p")
+	coded.Attrs.ID = "coded"
+	bottom.AppendChild(coded)
+	assert.Equal(t, "coded", coded.Attrs.ID)
+	assert.Equal(t, 1, len(e.GetElementByID("coded")))
+
+	bottom.RemoveChildren()
+	assert.Equal(t, 0, len(bottom.Element().Children))
+	assert.Assert(t, e.GetElementByID("mid") == nil)
+}
+
+func TestMutationsAfterShrink(t *testing.T) {
+	e := newRenderedEngine(t, hitTestTemplate, 10, 6)
+	bottom := e.GetElementByID("bottom")[0]
+	right := e.GetElementByID("right")[0]
+	top := e.GetElementByID("top")[0].Element()
+
+	// the terminal shrank and nothing was laid out again yet: the elements
+	// still carry boundaries outside the view
+	e.View.Resize(4, 1)
+	assert.NilError(t, e.GetElementByID("left")[0].SetAttribute("width", "2"))
+	assert.NilError(t, right.MoveTo(top))
+	right.Remove()
+	bottom.Remove()
+	assert.Assert(t, e.GetElementByID("bottom") == nil)
+	e.flushRenderStack()
+}
+
+func TestSetAttribute(t *testing.T) {
+	e := newRenderedEngine(t, `<html><head><style>.hot { color: 9; }</style></head>
+<body display="flex" flex-direction="column">
+	<div id="row" height="2"><p id="cell">cell</p></div>
+	<div id="rest"></div>
+</body>
+</html>`, 10, 6)
+	row := e.GetElementByID("row")[0]
+	cell := e.GetElementByID("cell")[0].Element()
+
+	assert.NilError(t, row.SetAttribute("background-color", "4"))
+	assert.Equal(t, dom.PaletteColor(4), cell.Attrs.BackGroundColor)
+	assert.Equal(t, 1, e.renderstack.Len())
+	assert.Equal(t, row.Element(), e.renderstack.Pop())
+
+	assert.NilError(t, row.SetAttribute("class", "hot"))
+	assert.Equal(t, dom.PaletteColor(9), cell.Attrs.Color)
+	assert.Equal(t, row.Element(), e.GetElementsByClassName("hot")[0].Element())
+	assert.Equal(t, e.DOM.Body, e.renderstack.Pop())
+
+	assert.NilError(t, row.SetAttribute("height", "4"))
+	e.flushRenderStack()
+	assert.Equal(t, 4, row.Element().Boundry.Height())
+	assert.Equal(t, 4, e.GetElementByID("rest")[0].Element().Boundry.FirstY)
+
+	assert.Assert(t, row.SetAttribute("style", "height") != nil)
+
+	row.SetContent("changed")
+	assert.Equal(t, "changed", row.Element().Content)
+	assert.Equal(t, row.Element(), e.renderstack.Pop())
+}
+
+func assertChildren(t *testing.T, parent *dom.Element, want ...*dom.Element) {
+	t.Helper()
+	assert.Equal(t, len(want), len(parent.Children))
+	for i := range want {
+		assert.Assert(t, parent.Children[i] == want[i], "child %d", i)
+	}
+}
+
+func TestRestoreOnPanic(t *testing.T) {
+	e := newRenderedEngine(t, `<html><body></body></html>`, 10, 4)
+	vt := tty.NewVirtualTTY(10, 4)
+	e.TTY = vt
+
+	func() {
+		defer func() {
+			assert.Equal(t, recover(), "boom", "the panic carries on")
+		}()
+		defer e.restoreOnPanic()
+		panic("boom")
+	}()
+	assert.Assert(t, vt.Closed())
+	assert.Assert(t, vt.Mode("?25"))
+}
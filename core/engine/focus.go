@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+)
+
+// --------------------
+//    State Styles
+// --------------------
+
+// StateStyle is laid over the attributes of an element while it is rendered
+// in a given state, such as focused or invalid. Default colors leave the
+// element's own colors untouched. Colors are inherited by the element's
+// children, the border is not.
+type StateStyle struct {
+	Color           dom.Color
+	BackGroundColor dom.Color
+	Border          bool
+}
+
+var (
+	DefaultFocusStyle = StateStyle{
+		BackGroundColor: dom.PaletteColor(240),
+	}
+	DefaultInvalidStyle = StateStyle{
+		Color: dom.PaletteColor(9),
+	}
+)
+
+func (ss StateStyle) apply(attrs dom.Attributes, self bool) *dom.Attributes {
+	if !ss.Color.IsDefault() {
+		attrs.Color = ss.Color
+	}
+	if !ss.BackGroundColor.IsDefault() {
+		attrs.BackGroundColor = ss.BackGroundColor
+	}
+	if self && ss.Border {
+		attrs.Border = true
+	}
+	return &attrs
+}
+
+func (e *Engine) SetFocusStyle(ss StateStyle) {
+	e.focusStyle = ss
+	if e.focused != nil {
+		e.renderstack.Push(e.focused)
+	}
+}
+
+func (e *Engine) SetInvalidStyle(ss StateStyle) {
+	e.invalidStyle = ss
+}
+
+// stateAttrs returns the attributes el is rendered with, after laying the
+// focus and invalid styles over its own.
+func (e *Engine) stateAttrs(el *dom.Element) *dom.Attributes {
+	attrs := el.Attrs
+	if f := e.focusedAncestor(el); f != nil {
+		attrs = e.focusStyle.apply(*attrs, f == el)
+	}
+	if el.State.Invalid {
+		attrs = e.invalidStyle.apply(*attrs, true)
+	}
+	return attrs
+}
+
+// --------------------
+//   Focus Management
+// --------------------
+
+// FocusedElement returns the element holding the keyboard focus, or nil.
+func (e *Engine) FocusedElement() Elementor {
+	if e.focused == nil {
+		return nil
+	}
+	return newElementUpdater(e.focused, e)
+}
+
+// Focus moves the keyboard focus to el, firing blur on the element losing
+// it and focus on el. Passing nil, or an element that is not focusable,
+// only blurs the current element.
+func (e *Engine) Focus(el *dom.Element) {
+	if el != nil && !isFocusable(el) {
+		el = nil
+	}
+	if el == e.focused {
+		return
+	}
+
+	restyle := e.DOM.UsesPseudo(dom.PseudoClass_Focus)
+
+	if prev := e.focused; prev != nil {
+		prev.State.Focused = false
+		e.focused = nil
+		e.renderstack.Push(prev)
+		if restyle {
+			e.restyle(prev)
+		}
+		e.dispatchEvent(prev, dom.EventType_Blur, nil)
+	}
+
+	if el != nil {
+		if el.Attrs.Writable {
+			el.State.Cursor = len([]rune(el.Content))
+		}
+		el.State.Focused = true
+		e.focused = el
+		e.renderstack.Push(el)
+		if restyle {
+			e.restyle(el)
+		}
+		e.dispatchEvent(el, dom.EventType_Focus, nil)
+	}
+}
+
+// releaseFocus drops the focus without re-rendering when it is held by el
+// or one of its descendants, which are about to leave the tree.
+func (e *Engine) releaseFocus(el *dom.Element) {
+	if e.focused == nil {
+		return
+	}
+	for p := e.focused; p != nil; p = p.Parent {
+		if p == el {
+			prev := e.focused
+			prev.State.Focused = false
+			e.focused = nil
+			e.dispatchEvent(prev, dom.EventType_Blur, nil)
+			return
+		}
+	}
+}
+
+// focusWithin focuses el or its closest focusable ancestor.
+func (e *Engine) focusWithin(el *dom.Element) {
+	for ; el != nil; el = el.Parent {
+		if isFocusable(el) {
+			e.Focus(el)
+			return
+		}
+	}
+}
+
+// focusedAncestor returns el or its closest ancestor that holds the focus.
+func (e *Engine) focusedAncestor(el *dom.Element) *dom.Element {
+	if e.focused == nil {
+		return nil
+	}
+	for ; el != nil; el = el.Parent {
+		if el == e.focused {
+			return el
+		}
+	}
+	return nil
+}
+
+// focusables lists the focusable elements under root in tree order.
+func focusables(root *dom.Element) []*dom.Element {
+	var list []*dom.Element
+	root.Walk(func(el *dom.Element) {
+		if isFocusable(el) {
+			list = append(list, el)
+		}
+	})
+	return list
+}
+
+// isFocusable reports whether el can hold the focus. Writable elements are
+// always focusable.
+func isFocusable(el *dom.Element) bool {
+	return el.Attrs.Focusable || el.Attrs.Writable
+}
+
+// focusGroup returns the closest ancestor of el marked as a focus group.
+func focusGroup(el *dom.Element) *dom.Element {
+	for p := el.Parent; p != nil; p = p.Parent {
+		if p.Attrs.FocusGroup {
+			return p
+		}
+	}
+	return nil
+}
+
+// moveFocus focuses the element by steps away from the current one among
+// the focusables of root, wrapping around at both ends. It reports whether
+// there was anything to focus.
+func (e *Engine) moveFocus(root *dom.Element, by int) bool {
+	list := focusables(root)
+	if len(list) == 0 {
+		return false
+	}
+
+	next := 0
+	if by < 0 {
+		next = len(list) - 1
+	}
+	for i, el := range list {
+		if el == e.focused {
+			next = ((i+by)%len(list) + len(list)) % len(list)
+			break
+		}
+	}
+
+	e.Focus(list[next])
+	return true
+}
+
+// handleFocusKey runs the focus navigation bound to ev and reports whether
+// the key was consumed.
+func (e *Engine) handleFocusKey(ev tty.KeyboardEvent) bool {
+	switch {
+	case isTab(ev):
+		return e.moveFocus(e.DOM.Body, 1)
+	case ev.Is(tty.SpecialKey_ShiftTab):
+		return e.moveFocus(e.DOM.Body, -1)
+	}
+
+	if e.focused == nil {
+		return false
+	}
+	group := focusGroup(e.focused)
+	if group == nil {
+		return false
+	}
+
+	switch {
+	case ev.Is(tty.SpecialKey_Down, tty.Modifier_None), ev.Is(tty.SpecialKey_Right, tty.Modifier_None):
+		return e.moveFocus(group, 1)
+	case ev.Is(tty.SpecialKey_Up, tty.Modifier_None), ev.Is(tty.SpecialKey_Left, tty.Modifier_None):
+		return e.moveFocus(group, -1)
+	}
+	return false
+}
+
+// isTab reports whether ev is the Tab key.
+func isTab(ev tty.KeyboardEvent) bool {
+	return ev.Is(tty.SpecialKey_Tab)
+}
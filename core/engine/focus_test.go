@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"gotest.tools/v3/assert"
+)
+
+var focusTemplate = `<html>
+<head></head>
+<body display="flex" flex-direction="column">
+	<p id="first" height="1" focusable="true">first</p>
+	<div id="menu" display="flex" height="1" focus-group="true">
+		<p id="open" focusable="true">open</p>
+		<p id="save" focusable="true">save</p>
+		<p id="quit" focusable="true">quit</p>
+	</div>
+	<div id="row" display="flex" height="1" focusable="true">
+		<p id="cell">cell</p>
+	</div>
+</body>
+</html>`
+
+var (
+	tabKey      = tty.KeyboardEvent{Key: tty.SpecialKey_Tab}
+	shiftTabKey = tty.KeyboardEvent{Key: tty.SpecialKey_ShiftTab}
+	rightKey    = tty.KeyboardEvent{Key: tty.SpecialKey_Right}
+	leftKey     = tty.KeyboardEvent{Key: tty.SpecialKey_Left}
+)
+
+func focusedID(e *Engine) string {
+	if f := e.FocusedElement(); f != nil {
+		return f.Element().Attrs.ID
+	}
+	return ""
+}
+
+func TestFocusNavigation(t *testing.T) {
+	e := newRenderedEngine(t, focusTemplate, 12, 3)
+
+	steps := []struct {
+		key      tty.KeyboardEvent
+		expected string
+	}{
+		{tabKey, "first"},
+		{tabKey, "open"},
+		{rightKey, "save"},
+		{rightKey, "quit"},
+		{rightKey, "open"},
+		{leftKey, "quit"},
+		{tabKey, "row"},
+		{tabKey, "first"},
+		{shiftTabKey, "row"},
+	}
+	for _, step := range steps {
+		_, forwarded := e.handleEvent(step.key)
+		assert.Assert(t, !forwarded)
+		assert.Equal(t, step.expected, focusedID(e))
+	}
+
+	// arrows outside of a focus group are left to the application
+	_, forwarded := e.handleEvent(rightKey)
+	assert.Assert(t, forwarded)
+	assert.Equal(t, "row", focusedID(e))
+}
+
+func TestFocusEvents(t *testing.T) {
+	e := newRenderedEngine(t, focusTemplate, 12, 3)
+	first := e.GetElementByID("first")[0].Element()
+	row := e.GetElementByID("row")[0].Element()
+
+	var log []string
+	first.AddEventListener(dom.EventType_Blur, func(ev *dom.Event) { log = append(log, "blur first") })
+	row.AddEventListener(dom.EventType_Focus, func(ev *dom.Event) { log = append(log, "focus row") })
+	row.AddEventListener(dom.EventType_Key, func(ev *dom.Event) { log = append(log, "key row") })
+
+	e.Focus(first)
+	assert.Assert(t, first.State.Focused)
+
+	// clicking a child focuses its focusable ancestor
+	e.handleEvent(tty.MouseEvent{X: 1, Y: 2, Button: tty.MouseButton_Left})
+	assert.Assert(t, !first.State.Focused)
+	assert.Equal(t, "row", focusedID(e))
+
+	e.handleEvent(tty.KeyboardEvent{Key: tty.NewByteKey('x')})
+	assert.DeepEqual(t, []string{"blur first", "focus row", "key row"}, log)
+
+	// elements that are not focusable only blur
+	e.Focus(e.GetElementByID("cell")[0].Element())
+	assert.Assert(t, e.FocusedElement() == nil)
+}
+
+func TestFocusStyle(t *testing.T) {
+	e := newRenderedEngine(t, focusTemplate, 12, 3)
+	e.Focus(e.GetElementByID("row")[0].Element())
+	e.flushRenderStack()
+
+	assert.Equal(t, DefaultFocusStyle.BackGroundColor, e.View.GetCell(0, 2).Style.Background)
+	assert.Equal(t, dom.ColorDefault, e.GetElementByID("cell")[0].Element().Attrs.BackGroundColor)
+
+	e.Focus(nil)
+	e.flushRenderStack()
+	assert.Equal(t, dom.ColorDefault, e.View.GetCell(0, 2).Style.Background)
+}
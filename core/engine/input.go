@@ -0,0 +1,356 @@
+package engine
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+// --------------------
+//    Input Editing
+// --------------------
+
+// handleInputKey applies ev to the writable element el and reports whether
+// the key was consumed. An input event is fired when the value changed.
+func (e *Engine) handleInputKey(el *dom.Element, ev tty.KeyboardEvent) bool {
+	value := []rune(el.Content)
+	cursor := graphemeFloor(value, clamp(el.State.Cursor, 0, len(value)))
+	word := ev.Modifiers&(tty.Modifier_Ctrl|tty.Modifier_Alt) != 0
+
+	switch {
+	case ev.Is(tty.SpecialKey_Left):
+		if word {
+			cursor = wordLeft(value, cursor)
+		} else {
+			cursor = prevGrapheme(value, cursor)
+		}
+	case ev.Is(tty.SpecialKey_Right):
+		if word {
+			cursor = wordRight(value, cursor)
+		} else {
+			cursor = nextGrapheme(value, cursor)
+		}
+	case ev.Is(tty.NewByteKey('b'), tty.Modifier_Alt):
+		cursor = wordLeft(value, cursor)
+	case ev.Is(tty.NewByteKey('f'), tty.Modifier_Alt):
+		cursor = wordRight(value, cursor)
+	case isHomeKey(ev):
+		cursor = 0
+	case isEndKey(ev):
+		cursor = len(value)
+	case ev.Is(tty.SpecialKey_Backspace, tty.Modifier_Alt), ev.Is(tty.NewByteKey('w'), tty.Modifier_Ctrl):
+		from := wordLeft(value, cursor)
+		value = append(value[:from], value[cursor:]...)
+		cursor = from
+	case ev.Is(tty.SpecialKey_Backspace), ev.Is(tty.NewByteKey('h'), tty.Modifier_Ctrl):
+		from := prevGrapheme(value, cursor)
+		value = append(value[:from], value[cursor:]...)
+		cursor = from
+	case ev.Is(tty.SpecialKey_Delete, tty.Modifier_Ctrl), ev.Is(tty.NewByteKey('d'), tty.Modifier_Alt):
+		to := wordRight(value, cursor)
+		value = append(value[:cursor], value[to:]...)
+	case ev.Is(tty.SpecialKey_Delete), ev.Is(tty.NewByteKey('d'), tty.Modifier_Ctrl):
+		to := nextGrapheme(value, cursor)
+		value = append(value[:cursor], value[to:]...)
+	case ev.Is(tty.NewByteKey('u'), tty.Modifier_Ctrl):
+		value = value[cursor:]
+		cursor = 0
+	case ev.Is(tty.NewByteKey('k'), tty.Modifier_Ctrl):
+		value = value[:cursor]
+	default:
+		r, ok := printableRune(ev)
+		if !ok {
+			return false
+		}
+		value = append(value[:cursor], append([]rune{r}, value[cursor:]...)...)
+		cursor++
+	}
+
+	el.State.Cursor = cursor
+	e.renderstack.Push(el)
+	if s := string(value); s != el.Content {
+		el.Content = s
+		e.dispatchEvent(el, dom.EventType_Input, nil)
+	}
+	return true
+}
+
+// placeInputCursor moves the caret of el to the character under screen x.
+func placeInputCursor(el *dom.Element, x int) {
+	gs := shownGraphemes(el)
+	i := graphemeAt(gs, el.State.ScrollX)
+	for col := x - contentBoundry(el).FirstX; i < len(gs)-1 && gs[i].width <= col; i++ {
+		col -= gs[i].width
+	}
+	el.State.Cursor = gs[i].start
+}
+
+// handleInputPaste inserts text at the caret of the writable element el.
+// Line breaks become spaces and other control characters are dropped, since
+// inputs hold a single line.
+func (e *Engine) handleInputPaste(el *dom.Element, text string) {
+	value := []rune(el.Content)
+	cursor := graphemeFloor(value, clamp(el.State.Cursor, 0, len(value)))
+
+	var ins []rune
+	for _, r := range strings.ReplaceAll(text, "\r\n", "\n") {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			ins = append(ins, ' ')
+		case isTextRune(r):
+			ins = append(ins, r)
+		}
+	}
+	value = append(value[:cursor], append(ins, value[cursor:]...)...)
+	// the pasted text may join the cluster after it, e.g. a combining mark
+	// or a zero width joiner
+	cursor = graphemeFloor(value, cursor+len(ins))
+
+	el.State.Cursor = cursor
+	e.renderstack.Push(el)
+	if s := string(value); s != el.Content {
+		el.Content = s
+		e.dispatchEvent(el, dom.EventType_Input, nil)
+	}
+}
+
+func printableRune(ev tty.KeyboardEvent) (rune, bool) {
+	r, ok := tty.KeyRune(ev.Key)
+	if !ok || ev.Modifiers != tty.Modifier_None {
+		return 0, false
+	}
+	if !unicode.IsPrint(r) {
+		return 0, false
+	}
+	return r, true
+}
+
+// isTextRune reports whether pasted text may hold r: printable runes and
+// the joiner that glues emoji sequences together.
+func isTextRune(r rune) bool {
+	return unicode.IsPrint(r) || r == '\u200d'
+}
+
+func isHomeKey(ev tty.KeyboardEvent) bool {
+	return ev.Is(tty.SpecialKey_Home) || ev.Is(tty.NewByteKey('a'), tty.Modifier_Ctrl)
+}
+
+func isEndKey(ev tty.KeyboardEvent) bool {
+	return ev.Is(tty.SpecialKey_End) || ev.Is(tty.NewByteKey('e'), tty.Modifier_Ctrl)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordLeft returns the offset of the start of the word before cursor.
+func wordLeft(value []rune, cursor int) int {
+	for cursor > 0 && !isWordRune(value[cursor-1]) {
+		cursor--
+	}
+	for cursor > 0 && isWordRune(value[cursor-1]) {
+		cursor--
+	}
+	return graphemeFloor(value, cursor)
+}
+
+// wordRight returns the offset of the end of the word after cursor.
+func wordRight(value []rune, cursor int) int {
+	for cursor < len(value) && !isWordRune(value[cursor]) {
+		cursor++
+	}
+	for cursor < len(value) && isWordRune(value[cursor]) {
+		cursor++
+	}
+	if f := graphemeFloor(value, cursor); f != cursor {
+		return nextGrapheme(value, f)
+	}
+	return cursor
+}
+
+// graphemeStarts returns the offsets at which the grapheme clusters of rs
+// start, followed by len(rs). The caret only ever sits at one of them.
+func graphemeStarts(rs []rune) []int {
+	starts := []int{0}
+	s := string(rs)
+	for n := 0; s != ""; {
+		g, _ := view.FirstGrapheme(s)
+		s = s[len(g):]
+		n += utf8.RuneCountInString(g)
+		starts = append(starts, n)
+	}
+	return starts
+}
+
+// prevGrapheme returns the start of the cluster of rs before offset i.
+func prevGrapheme(rs []rune, i int) int {
+	starts := graphemeStarts(rs)
+	for k := len(starts) - 1; k >= 0; k-- {
+		if starts[k] < i {
+			return starts[k]
+		}
+	}
+	return 0
+}
+
+// nextGrapheme returns the start of the cluster of rs after offset i.
+func nextGrapheme(rs []rune, i int) int {
+	for _, s := range graphemeStarts(rs) {
+		if s > i {
+			return s
+		}
+	}
+	return len(rs)
+}
+
+// graphemeFloor returns the start of the cluster of rs offset i is in.
+func graphemeFloor(rs []rune, i int) int {
+	floor := 0
+	for _, s := range graphemeStarts(rs) {
+		if s > i {
+			break
+		}
+		floor = s
+	}
+	return floor
+}
+
+// --------------------
+//   Input Validation
+// --------------------
+
+var (
+	emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	telRegexp   = regexp.MustCompile(`^\+?[0-9][0-9 ().\-]*$`)
+)
+
+// validInput reports whether value is acceptable for the given input type.
+// An empty value is always valid.
+func validInput(t dom.InputType, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return true
+	}
+
+	switch t {
+	case dom.InputType_Number:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case dom.InputType_Email:
+		return emailRegexp.MatchString(value)
+	case dom.InputType_Tel:
+		return telRegexp.MatchString(value)
+	case dom.InputType_URL:
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	default:
+		return true
+	}
+}
+
+// --------------------
+//    Input Rendering
+// --------------------
+
+var (
+	caretForeground = dom.PaletteColor(16)
+	caretBackground = dom.PaletteColor(15)
+)
+
+// renderInput prints the value of a single line writable element on its
+// first content row, scrolled horizontally so that the caret stays visible.
+func renderInput(elem *dom.Element, v View) {
+	b := contentBoundry(elem)
+	width := b.Width()
+	if width < 1 || b.Height() < 1 {
+		return
+	}
+
+	gs := shownGraphemes(elem)
+	cursor := graphemeAt(gs, elem.State.Cursor)
+	scroll := graphemeAt(gs, elem.State.ScrollX)
+	if cursor < scroll {
+		scroll = cursor
+	}
+	// wide characters take two columns, the caret at least one
+	caret := gs[cursor].text
+	if caret == "" {
+		caret = " "
+	}
+	for scroll < cursor && graphemesWidth(gs[scroll:cursor])+view.StringWidth(caret) > width {
+		scroll++
+	}
+	elem.State.Cursor, elem.State.ScrollX = gs[cursor].start, gs[scroll].start
+
+	var shown strings.Builder
+	for _, g := range gs[scroll:] {
+		shown.WriteString(g.text)
+	}
+	style := textStyle(elem.Attrs)
+	v.PrintText(b.FirstX, b.FirstY, width, style, elem.Attrs.ZIndex, elem, shown.String())
+
+	if elem.State.Focused {
+		col := graphemesWidth(gs[scroll:cursor])
+		caretStyle := textStyle(elem.Attrs)
+		caretStyle.Foreground, caretStyle.Background = caretForeground, caretBackground
+		if !elem.Attrs.Color.IsDefault() {
+			caretStyle.Background = elem.Attrs.Color
+		}
+		if !elem.Attrs.BackGroundColor.IsDefault() {
+			caretStyle.Foreground = elem.Attrs.BackGroundColor
+		}
+		v.PrintText(b.FirstX+col, b.FirstY, width-col, caretStyle, elem.Attrs.ZIndex, elem, caret)
+	}
+}
+
+// shownGrapheme is a grapheme cluster of the value of an input as it is
+// shown, along with the offset of its first rune in the value.
+type shownGrapheme struct {
+	text  string
+	width int
+	start int
+}
+
+// shownGraphemes splits the value of an input into the clusters it shows,
+// each a * for passwords. An empty cluster at the end of the value stands
+// for the caret position after it.
+func shownGraphemes(el *dom.Element) []shownGrapheme {
+	var gs []shownGrapheme
+	s, start := el.Content, 0
+	for s != "" {
+		g, w := view.FirstGrapheme(s)
+		s = s[len(g):]
+		text := g
+		if el.Attrs.TextType == dom.InputType_Password {
+			text, w = "*", 1
+		}
+		gs = append(gs, shownGrapheme{text: text, width: w, start: start})
+		start += utf8.RuneCountInString(g)
+	}
+	return append(gs, shownGrapheme{start: start})
+}
+
+// graphemeAt returns the index of the cluster of gs rune offset i is in.
+func graphemeAt(gs []shownGrapheme, i int) int {
+	k := 0
+	for k < len(gs)-1 && gs[k+1].start <= i {
+		k++
+	}
+	return k
+}
+
+// graphemesWidth returns the number of columns the clusters take.
+func graphemesWidth(gs []shownGrapheme) int {
+	n := 0
+	for _, g := range gs {
+		n += g.width
+	}
+	return n
+}
@@ -0,0 +1,273 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"gotest.tools/v3/assert"
+)
+
+var inputTemplate = `<html>
+<head></head>
+<body display="flex" flex-direction="column">
+	<input id="user" height="1" writable="true"></input>
+	<input id="pass" height="1" writable="true" text-type="password"></input>
+	<input id="age" height="1" writable="true" text-type="number"></input>
+</body>
+</html>`
+
+func typeText(e *Engine, s string) {
+	for _, r := range s {
+		var key tty.Key = tty.NewRuneKey(r)
+		if r < 0x80 {
+			key = tty.NewByteKey(byte(r))
+		}
+		e.handleEvent(tty.KeyboardEvent{Key: key})
+	}
+}
+
+func rowText(e *Engine, y int) string {
+	var sb strings.Builder
+	for x := 0; x < int(e.View.Width()); x++ {
+		sb.WriteString(e.View.GetCell(x, y).Content)
+	}
+	return sb.String()
+}
+
+func TestInputEditing(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	user := e.GetElementByID("user")[0].Element()
+	e.Focus(user)
+
+	var inputs int
+	user.AddEventListener(dom.EventType_Input, func(ev *dom.Event) { inputs++ })
+
+	typeText(e, "hello world")
+	assert.Equal(t, "hello world", user.Content)
+	assert.Equal(t, 11, user.State.Cursor)
+
+	steps := []struct {
+		key      tty.KeyboardEvent
+		content  string
+		expected int
+	}{
+		{tty.KeyboardEvent{Key: tty.SpecialKey_Left, Modifiers: tty.Modifier_Ctrl}, "hello world", 6},
+		{tty.KeyboardEvent{Key: tty.SpecialKey_Left}, "hello world", 5},
+		{tty.KeyboardEvent{Key: tty.SpecialKey_Backspace}, "hell world", 4},
+		{tty.KeyboardEvent{Key: tty.NewByteKey('w'), Modifiers: tty.Modifier_Ctrl}, " world", 0},
+		{tty.KeyboardEvent{Key: tty.SpecialKey_Delete}, "world", 0},
+		{tty.KeyboardEvent{Key: tty.SpecialKey_End}, "world", 5},
+		{tty.KeyboardEvent{Key: tty.NewByteKey('a'), Modifiers: tty.Modifier_Ctrl}, "world", 0},
+		{tty.KeyboardEvent{Key: tty.NewByteKey('f'), Modifiers: tty.Modifier_Alt}, "world", 5},
+	}
+	for _, step := range steps {
+		_, forwarded := e.handleEvent(step.key)
+		assert.Assert(t, !forwarded)
+		assert.Equal(t, step.content, user.Content)
+		assert.Equal(t, step.expected, user.State.Cursor)
+	}
+	assert.Equal(t, 14, inputs)
+
+	// keys the input has no use for still reach the application
+	_, forwarded := e.handleEvent(tty.KeyboardEvent{Key: tty.SpecialKey_Enter})
+	assert.Assert(t, forwarded)
+}
+
+func TestInputRender(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	user := e.GetElementByID("user")[0].Element()
+	pass := e.GetElementByID("pass")[0].Element()
+
+	e.Focus(user)
+	typeText(e, "abcdefghij")
+	e.flushRenderStack()
+	assert.Equal(t, "defghij ", rowText(e, 0))
+	assert.Equal(t, caretBackground, e.View.GetCell(7, 0).Style.Background)
+
+	e.handleEvent(tty.KeyboardEvent{Key: tty.SpecialKey_Home})
+	e.flushRenderStack()
+	assert.Equal(t, "abcdefgh", rowText(e, 0))
+
+	e.Focus(pass)
+	typeText(e, "secret")
+	e.flushRenderStack()
+	assert.Equal(t, "secret", pass.Content)
+	assert.Equal(t, "****** ", rowText(e, 1)[:7])
+}
+
+func TestInputRenderWide(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	user := e.GetElementByID("user")[0].Element()
+
+	e.Focus(user)
+	typeText(e, "漢字かな")
+	e.flushRenderStack()
+	// the caret after four wide characters needs a ninth column
+	assert.Equal(t, "字かな  ", rowText(e, 0))
+	assert.Equal(t, caretBackground, e.View.GetCell(6, 0).Style.Background)
+
+	e.handleEvent(tty.KeyboardEvent{Key: tty.SpecialKey_Home})
+	e.flushRenderStack()
+	assert.Equal(t, "漢字かな", rowText(e, 0))
+	assert.Equal(t, caretBackground, e.View.GetCell(1, 0).Style.Background)
+
+	// both columns of a wide character are on it
+	placeInputCursor(user, 3)
+	assert.Equal(t, 1, user.State.Cursor)
+	placeInputCursor(user, 4)
+	assert.Equal(t, 2, user.State.Cursor)
+}
+
+func TestInputGraphemes(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 12, 3)
+	user := e.GetElementByID("user")[0]
+	e.Focus(user.Element())
+
+	e.handleEvent(tty.PasteEvent{Text: "ae\u0301🇮🇷👨‍👩‍👧"})
+	assert.Equal(t, "ae\u0301🇮🇷👨‍👩‍👧", user.Element().Content)
+
+	// the caret steps over whole clusters and backspace deletes one
+	press(e, tty.SpecialKey_Backspace)
+	assert.Equal(t, "ae\u0301🇮🇷", user.Element().Content)
+	press(e, tty.SpecialKey_Left)
+	assert.Equal(t, 3, user.Element().State.Cursor)
+	press(e, tty.SpecialKey_Left)
+	assert.Equal(t, 1, user.Element().State.Cursor)
+	press(e, tty.SpecialKey_Delete)
+	assert.Equal(t, "a🇮🇷", user.Element().Content)
+	press(e, tty.SpecialKey_Right)
+	press(e, tty.SpecialKey_Backspace)
+	assert.Equal(t, "a", user.Element().Content)
+
+	// a joined emoji takes the two columns it is drawn in
+	user.SetContent("👨‍👩‍👧x")
+	e.flushRenderStack()
+	assert.Equal(t, "👨‍👩‍👧x", strings.TrimRight(rowText(e, 0), " "))
+	assert.Equal(t, caretBackground, e.View.GetCell(3, 0).Style.Background)
+	placeInputCursor(user.Element(), 1)
+	assert.Equal(t, 0, user.Element().State.Cursor)
+	e.placeCursor(user.Element(), 2, 0)
+	assert.Equal(t, 5, user.Element().State.Cursor)
+	e.flushRenderStack()
+	assert.Equal(t, caretBackground, e.View.GetCell(2, 0).Style.Background)
+}
+
+func TestInputPasteJoinsCluster(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 12, 3)
+	user := e.GetElementByID("user")[0]
+	e.Focus(user.Element())
+
+	// a joiner pasted between two emoji makes a single cluster of them, so
+	// the caret moves back to its start
+	user.SetContent("👨👩x")
+	user.Element().State.Cursor = 1
+	e.handleEvent(tty.PasteEvent{Text: "\u200d"})
+	assert.Equal(t, "👨\u200d👩x", user.Element().Content)
+	assert.Equal(t, 0, user.Element().State.Cursor)
+
+	// a regional indicator pasted before a flag pairs with its first half
+	user.SetContent("🇮🇷")
+	user.Element().State.Cursor = 0
+	e.handleEvent(tty.PasteEvent{Text: "🇮"})
+	assert.Equal(t, 0, user.Element().State.Cursor)
+	press(e, tty.SpecialKey_Delete)
+	assert.Equal(t, "🇷", user.Element().Content)
+}
+
+func TestInputValidation(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	age := e.GetElementByID("age")[0].Element()
+	e.Focus(age)
+
+	typeText(e, "4x")
+	e.flushRenderStack()
+	assert.Assert(t, age.State.Invalid)
+	assert.Equal(t, DefaultInvalidStyle.Color, e.View.GetCell(0, 2).Style.Foreground)
+
+	e.handleEvent(tty.KeyboardEvent{Key: tty.SpecialKey_Backspace})
+	e.flushRenderStack()
+	assert.Assert(t, !age.State.Invalid)
+}
+
+type validInputTestSuite struct {
+	name     string
+	typ      dom.InputType
+	value    string
+	expected bool
+}
+
+var validInputTestSuites = []validInputTestSuite{
+	{name: "empty number", typ: dom.InputType_Number, value: "", expected: true},
+	{name: "number", typ: dom.InputType_Number, value: "-12.5", expected: true},
+	{name: "not a number", typ: dom.InputType_Number, value: "12a", expected: false},
+	{name: "email", typ: dom.InputType_Email, value: "sam@example.com", expected: true},
+	{name: "email without domain", typ: dom.InputType_Email, value: "sam@", expected: false},
+	{name: "tel", typ: dom.InputType_Tel, value: "+1 (555) 010-9999", expected: true},
+	{name: "tel with letters", typ: dom.InputType_Tel, value: "555-CALL", expected: false},
+	{name: "url", typ: dom.InputType_URL, value: "https://example.com/a", expected: true},
+	{name: "url without scheme", typ: dom.InputType_URL, value: "example.com", expected: false},
+	{name: "text", typ: dom.InputType_Text, value: "anything @ all", expected: true},
+}
+
+func TestValidInput(t *testing.T) {
+	for _, suite := range validInputTestSuites {
+		t.Run(suite.name, func(t *testing.T) {
+			assert.Equal(t, suite.expected, validInput(suite.typ, suite.value))
+		})
+	}
+}
+
+func TestInputRunes(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	user := e.GetElementByID("user")[0].Element()
+	e.Focus(user)
+
+	typeText(e, "café ñ")
+	assert.Equal(t, "café ñ", user.Content)
+	assert.Equal(t, 6, user.State.Cursor)
+}
+
+func TestInputPaste(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	user := e.GetElementByID("user")[0].Element()
+
+	// without a writable element in focus the paste is forwarded
+	ev, forwarded := e.handleEvent(tty.PasteEvent{Text: "q"})
+	assert.Assert(t, forwarded)
+	assert.Equal(t, ev, tty.Event(tty.PasteEvent{Text: "q"}))
+
+	e.Focus(user)
+	var inputs int
+	user.AddEventListener(dom.EventType_Input, func(ev *dom.Event) { inputs++ })
+
+	typeText(e, "ab")
+	press(e, tty.SpecialKey_Left)
+	_, forwarded = e.handleEvent(tty.PasteEvent{Text: "x\ny\x07é"})
+	assert.Assert(t, !forwarded)
+	assert.Equal(t, "ax yéb", user.Content)
+	assert.Equal(t, 5, user.State.Cursor)
+	assert.Equal(t, 3, inputs, "one input event for the whole paste")
+
+	// a paste listener can keep the text out
+	user.AddEventListener(dom.EventType_Paste, func(ev *dom.Event) { ev.PreventDefault() })
+	e.handleEvent(tty.PasteEvent{Text: "zzz"})
+	assert.Equal(t, "ax yéb", user.Content)
+}
+
+func TestInputKeyRelease(t *testing.T) {
+	e := newRenderedEngine(t, inputTemplate, 8, 3)
+	user := e.GetElementByID("user")[0].Element()
+	e.Focus(user)
+
+	e.handleEvent(tty.KeyboardEvent{Key: tty.NewByteKey('a')})
+	e.handleEvent(tty.KeyboardEvent{Key: tty.NewByteKey('a'), Action: tty.KeyAction_Repeat})
+	_, forwarded := e.handleEvent(tty.KeyboardEvent{Key: tty.NewByteKey('a'), Action: tty.KeyAction_Release})
+	assert.Assert(t, forwarded)
+	assert.Equal(t, "aa", user.Content)
+
+	_, forwarded = e.handleEvent(tty.KeyboardEvent{Key: tty.SpecialKey_Tab, Action: tty.KeyAction_Release})
+	assert.Assert(t, forwarded)
+	assert.Equal(t, e.focused, user, "a released Tab does not move the focus")
+}
@@ -10,51 +10,79 @@ func drawBorder(elem *dom.Element, v View) {
 		return
 	}
 	boundry := elem.Boundry.Indexify()
-	v.PrintRuneRepeat(boundry.FirstX, boundry.FirstY, elem.Attrs.Color, elem.Attrs.BackGroundColor, boundry.Width(), elem.Attrs.ZIndex, view.AxisMask_X, '─')
-	v.PrintRuneRepeat(boundry.FirstX, boundry.SecondY, elem.Attrs.Color, elem.Attrs.BackGroundColor, boundry.Width(), elem.Attrs.ZIndex, view.AxisMask_X, '─')
-	v.PrintRuneRepeat(boundry.FirstX, boundry.FirstY, elem.Attrs.Color, elem.Attrs.BackGroundColor, boundry.Height(), elem.Attrs.ZIndex, view.AxisMask_Y, '│')
-	v.PrintRuneRepeat(boundry.SecondX, boundry.FirstY, elem.Attrs.Color, elem.Attrs.BackGroundColor, boundry.Height(), elem.Attrs.ZIndex, view.AxisMask_Y, '│')
+	style := boxStyle(elem.Attrs)
+	v.PrintRuneRepeat(boundry.FirstX, boundry.FirstY, style, boundry.Width(), elem.Attrs.ZIndex, elem, view.AxisMask_X, '─')
+	v.PrintRuneRepeat(boundry.FirstX, boundry.SecondY, style, boundry.Width(), elem.Attrs.ZIndex, elem, view.AxisMask_X, '─')
+	v.PrintRuneRepeat(boundry.FirstX, boundry.FirstY, style, boundry.Height(), elem.Attrs.ZIndex, elem, view.AxisMask_Y, '│')
+	v.PrintRuneRepeat(boundry.SecondX, boundry.FirstY, style, boundry.Height(), elem.Attrs.ZIndex, elem, view.AxisMask_Y, '│')
 
-	v.PrintRune(boundry.FirstX, boundry.FirstY, elem.Attrs.Color, elem.Attrs.BackGroundColor, elem.Attrs.ZIndex, '┌')
-	v.PrintRune(boundry.SecondX, boundry.FirstY, elem.Attrs.Color, elem.Attrs.BackGroundColor, elem.Attrs.ZIndex, '┐')
+	v.PrintRune(boundry.FirstX, boundry.FirstY, style, elem.Attrs.ZIndex, elem, '┌')
+	v.PrintRune(boundry.SecondX, boundry.FirstY, style, elem.Attrs.ZIndex, elem, '┐')
 
-	v.PrintRune(boundry.FirstX, boundry.SecondY, elem.Attrs.Color, elem.Attrs.BackGroundColor, elem.Attrs.ZIndex, '└')
-	v.PrintRune(boundry.SecondX, boundry.SecondY, elem.Attrs.Color, elem.Attrs.BackGroundColor, elem.Attrs.ZIndex, '┘')
-}
-
-func renderText(elem *dom.Element, v View) dom.Boundry {
-	boundry := elem.Boundry
-	if elem.Attrs.Border {
-		boundry = boundry.Shrink(1)
-	}
-	text := elem.Content
-	n := 0
-	if text == "" || boundry.Width() < 1 || boundry.Height() < 1 {
-		return boundry
-	}
-
-	boundry = boundry.Indexify()
-
-c:
-	for y := 0; y <= boundry.Height(); y++ {
-		if y >= elem.State.ScrollY {
-			for x := 0; x <= boundry.Width(); x++ {
-				if x >= len(text) {
-					break c
-				}
-				v.PrintRune(boundry.FirstX+x, boundry.FirstY+y, elem.Attrs.Color, elem.Attrs.BackGroundColor, elem.Attrs.ZIndex, rune(text[x]))
-			}
-			text = text[boundry.Width()+1:]
-		}
-		n++
-	}
-	return boundry.ShrinkMask(n, dom.PositionMaskTop)
+	v.PrintRune(boundry.FirstX, boundry.SecondY, style, elem.Attrs.ZIndex, elem, '└')
+	v.PrintRune(boundry.SecondX, boundry.SecondY, style, elem.Attrs.ZIndex, elem, '┘')
 }
 
 func renderBase(elem *dom.Element, v View) {
+	style := boxStyle(elem.Attrs)
 	for y := elem.Boundry.FirstY; y < elem.Boundry.SecondY; y++ {
 		for x := elem.Boundry.FirstX; x < elem.Boundry.SecondX; x++ {
-			v.PrintRune(x, y, elem.Attrs.Color, elem.Attrs.BackGroundColor, elem.Attrs.ZIndex, ' ')
+			v.PrintRune(x, y, style, elem.Attrs.ZIndex, elem, ' ')
 		}
 	}
 }
+
+// boxStyle returns the style of the background and the border of an
+// element: its colors, reversed when it asks so.
+func boxStyle(attrs *dom.Attributes) view.Style {
+	style := view.NewStyle(attrs.Color, attrs.BackGroundColor)
+	if attrs.Reverse {
+		style.Attrs |= view.Attr_Reverse
+	}
+	return style
+}
+
+// textStyle returns the style of the text of an element, which adds its
+// font and decoration to boxStyle.
+func textStyle(attrs *dom.Attributes) view.Style {
+	style := boxStyle(attrs)
+	switch attrs.FontWeight {
+	case dom.FontWeight_Bold:
+		style.Attrs |= view.Attr_Bold
+	case dom.FontWeight_Dim:
+		style.Attrs |= view.Attr_Dim
+	}
+	if attrs.FontStyle == dom.FontStyle_Italic {
+		style.Attrs |= view.Attr_Italic
+	}
+	if attrs.TextDecoration&dom.TextDecoration_Underline != 0 {
+		style.Attrs |= view.Attr_Underline
+		style.UnderlineStyle = attrs.DecorationStyle
+		style.UnderlineColor = attrs.DecorationColor
+	}
+	if attrs.TextDecoration&dom.TextDecoration_LineThrough != 0 {
+		style.Attrs |= view.Attr_Strikethrough
+	}
+	if attrs.TextDecoration&dom.TextDecoration_Blink != 0 {
+		style.Attrs |= view.Attr_Blink
+	}
+	return style
+}
+
+// contentBoundry returns the boundry of elem without its border.
+func contentBoundry(elem *dom.Element) dom.Boundry {
+	if elem.Attrs.Border {
+		return elem.Boundry.Shrink(1)
+	}
+	return elem.Boundry
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
@@ -233,12 +233,41 @@ func (a *Absolute) Layout(ctx context.Context, elem *dom.Element, boundry dom.Bo
 	return nil
 }
 
+type Input struct {
+	View      View
+	rndstck   RenderStack
+	textareas *textAreaStore
+}
+
+func newInputLayout(v View, rndstck RenderStack, textareas *textAreaStore) *Input {
+	return &Input{
+		View:      v,
+		rndstck:   rndstck,
+		textareas: textareas,
+	}
+}
+
+func (i *Input) Layout(ctx context.Context, elem *dom.Element, boundry dom.Boundry) error {
+	if elem.Attrs.Display == dom.Display_Absolute {
+		elem.Boundry = dom.NewBoundry(elem.Attrs.Left, elem.Attrs.Top, elem.Attrs.Width+elem.Attrs.Left, elem.Attrs.Height+elem.Attrs.Top)
+	}
+	renderBase(elem, i.View)
+	drawBorder(elem, i.View)
+	if isTextArea(elem) {
+		renderTextArea(elem, i.textareas.Get(elem), i.View)
+		return nil
+	}
+	renderInput(elem, i.View)
+	return nil
+}
+
 type LayoutType string
 
 const (
 	LayoutType_Flex     LayoutType = "flex"
 	LayoutType_Block    LayoutType = "block"
 	LayoutType_Absolute LayoutType = "absolute"
+	LayoutType_Input    LayoutType = "input"
 )
 
 type RenderFlag uint16
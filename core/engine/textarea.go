@@ -0,0 +1,591 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+// --------------------
+//   TextArea Editor
+// --------------------
+
+const textAreaHistoryLimit = 100
+
+type textPos struct {
+	Line int
+	Col  int
+}
+
+func (p textPos) before(o textPos) bool {
+	return p.Line < o.Line || p.Line == o.Line && p.Col < o.Col
+}
+
+type textAreaSnapshot struct {
+	lines  [][]rune
+	cursor textPos
+}
+
+type editKind uint8
+
+const (
+	editKind_None editKind = iota
+	editKind_Insert
+	editKind_Delete
+	editKind_Other
+)
+
+// textArea is the editing state of a multi-line writable element. The
+// element's Content is kept in sync with the buffer after every edit.
+type textArea struct {
+	lines  [][]rune
+	cursor textPos
+	// anchor is the other end of the selection while one is active.
+	anchor *textPos
+	// goalCol is the visual column kept while moving up and down.
+	goalCol int
+
+	undo     []textAreaSnapshot
+	redo     []textAreaSnapshot
+	lastEdit editKind
+	synced   string
+}
+
+func newTextArea(content string) *textArea {
+	ta := &textArea{}
+	ta.load(content)
+	return ta
+}
+
+func isTextArea(el *dom.Element) bool {
+	return el.Attrs.Writable && el.Name == "textarea"
+}
+
+func (ta *textArea) load(content string) {
+	ta.lines = nil
+	for _, l := range strings.Split(content, "\n") {
+		ta.lines = append(ta.lines, []rune(l))
+	}
+	ta.cursor = textPos{}
+	ta.anchor = nil
+	ta.undo, ta.redo = nil, nil
+	ta.lastEdit = editKind_None
+	ta.synced = content
+}
+
+func (ta *textArea) Value() string {
+	ls := make([]string, len(ta.lines))
+	for i, l := range ta.lines {
+		ls[i] = string(l)
+	}
+	return strings.Join(ls, "\n")
+}
+
+func (ta *textArea) snapshot() textAreaSnapshot {
+	lines := make([][]rune, len(ta.lines))
+	for i, l := range ta.lines {
+		lines[i] = append([]rune(nil), l...)
+	}
+	return textAreaSnapshot{lines: lines, cursor: ta.cursor}
+}
+
+func (ta *textArea) restore(s textAreaSnapshot) {
+	ta.lines = s.lines
+	ta.cursor = s.cursor
+	ta.anchor = nil
+}
+
+// record saves the buffer on the undo history before an edit of the given
+// kind. Consecutive inserts or deletes are merged into one undo step.
+func (ta *textArea) record(kind editKind) {
+	if kind != editKind_Other && kind == ta.lastEdit {
+		return
+	}
+	ta.undo = append(ta.undo, ta.snapshot())
+	if len(ta.undo) > textAreaHistoryLimit {
+		ta.undo = ta.undo[1:]
+	}
+	ta.redo = nil
+	ta.lastEdit = kind
+}
+
+func (ta *textArea) Undo() bool {
+	if len(ta.undo) == 0 {
+		return false
+	}
+	ta.redo = append(ta.redo, ta.snapshot())
+	ta.restore(ta.undo[len(ta.undo)-1])
+	ta.undo = ta.undo[:len(ta.undo)-1]
+	ta.lastEdit = editKind_None
+	return true
+}
+
+func (ta *textArea) Redo() bool {
+	if len(ta.redo) == 0 {
+		return false
+	}
+	ta.undo = append(ta.undo, ta.snapshot())
+	ta.restore(ta.redo[len(ta.redo)-1])
+	ta.redo = ta.redo[:len(ta.redo)-1]
+	ta.lastEdit = editKind_None
+	return true
+}
+
+// selection returns the ordered ends of the active selection.
+func (ta *textArea) selection() (textPos, textPos, bool) {
+	if ta.anchor == nil || *ta.anchor == ta.cursor {
+		return textPos{}, textPos{}, false
+	}
+	if ta.anchor.before(ta.cursor) {
+		return *ta.anchor, ta.cursor, true
+	}
+	return ta.cursor, *ta.anchor, true
+}
+
+func (ta *textArea) selected(p textPos) bool {
+	from, to, ok := ta.selection()
+	return ok && !p.before(from) && p.before(to)
+}
+
+// deleteRange removes the text between from and to and leaves the cursor
+// at from.
+func (ta *textArea) deleteRange(from, to textPos) {
+	head := ta.lines[from.Line][:from.Col]
+	tail := ta.lines[to.Line][to.Col:]
+	line := append(append([]rune(nil), head...), tail...)
+	ta.lines = append(ta.lines[:from.Line], append([][]rune{line}, ta.lines[to.Line+1:]...)...)
+	ta.cursor = from
+	ta.anchor = nil
+}
+
+// deleteSelection removes the selected text and reports whether there was
+// any.
+func (ta *textArea) deleteSelection() bool {
+	from, to, ok := ta.selection()
+	if !ok {
+		ta.anchor = nil
+		return false
+	}
+	ta.deleteRange(from, to)
+	return true
+}
+
+func (ta *textArea) insert(r rune) {
+	ta.deleteSelection()
+	line := ta.lines[ta.cursor.Line]
+	c := ta.cursor.Col
+	ta.lines[ta.cursor.Line] = append(line[:c], append([]rune{r}, line[c:]...)...)
+	ta.cursor.Col++
+}
+
+func (ta *textArea) newline() {
+	ta.deleteSelection()
+	line := ta.lines[ta.cursor.Line]
+	c := ta.cursor.Col
+	head := append([]rune(nil), line[:c]...)
+	tail := append([]rune(nil), line[c:]...)
+	ta.lines[ta.cursor.Line] = head
+	ta.lines = append(ta.lines[:ta.cursor.Line+1], append([][]rune{tail}, ta.lines[ta.cursor.Line+1:]...)...)
+	ta.cursor = textPos{Line: ta.cursor.Line + 1}
+}
+
+func (ta *textArea) backspace() {
+	if ta.deleteSelection() {
+		return
+	}
+	from := ta.cursor
+	switch {
+	case from.Col > 0:
+		from.Col = prevGrapheme(ta.lines[from.Line], from.Col)
+	case from.Line > 0:
+		from.Line--
+		from.Col = len(ta.lines[from.Line])
+	default:
+		return
+	}
+	ta.deleteRange(from, ta.cursor)
+}
+
+func (ta *textArea) delete() {
+	if ta.deleteSelection() {
+		return
+	}
+	to := ta.cursor
+	switch {
+	case to.Col < len(ta.lines[to.Line]):
+		to.Col = nextGrapheme(ta.lines[to.Line], to.Col)
+	case to.Line < len(ta.lines)-1:
+		to.Line++
+		to.Col = 0
+	default:
+		return
+	}
+	ta.deleteRange(ta.cursor, to)
+}
+
+// move places the cursor at p, extending the selection when extend is set
+// and dropping it otherwise.
+func (ta *textArea) move(p textPos, extend bool) {
+	if extend {
+		if ta.anchor == nil {
+			a := ta.cursor
+			ta.anchor = &a
+		}
+	} else {
+		ta.anchor = nil
+	}
+	ta.cursor = p
+	ta.lastEdit = editKind_None
+}
+
+func (ta *textArea) left(p textPos) textPos {
+	if p.Col > 0 {
+		p.Col = prevGrapheme(ta.lines[p.Line], p.Col)
+	} else if p.Line > 0 {
+		p.Line--
+		p.Col = len(ta.lines[p.Line])
+	}
+	return p
+}
+
+func (ta *textArea) right(p textPos) textPos {
+	if p.Col < len(ta.lines[p.Line]) {
+		p.Col = nextGrapheme(ta.lines[p.Line], p.Col)
+	} else if p.Line < len(ta.lines)-1 {
+		p.Line++
+		p.Col = 0
+	}
+	return p
+}
+
+// vertical moves p by the given number of visual rows of the given width,
+// keeping the goal column.
+func (ta *textArea) vertical(p textPos, by, width int) textPos {
+	row, _ := ta.visualPos(p, width)
+	return ta.posAt(row+by, ta.goalCol, width)
+}
+
+// posAt returns the buffer position shown at the given visual row and
+// column, clamped to the text.
+func (ta *textArea) posAt(row, col, width int) textPos {
+	if row < 0 {
+		return textPos{}
+	}
+	for line, l := range ta.lines {
+		starts := rowStarts(l, width)
+		if row >= len(starts) {
+			row -= len(starts)
+			continue
+		}
+		i, end := starts[row], len(l)
+		if row+1 < len(starts) {
+			end = starts[row+1]
+		}
+		for i < end {
+			g, w := view.FirstGrapheme(string(l[i:end]))
+			if w > col {
+				break
+			}
+			col -= w
+			i += utf8.RuneCountInString(g)
+		}
+		if i == end && end < len(l) {
+			// the end of a wrapped row is the start of the next
+			i = prevGrapheme(l, i)
+		}
+		return textPos{Line: line, Col: i}
+	}
+	last := len(ta.lines) - 1
+	return textPos{Line: last, Col: len(ta.lines[last])}
+}
+
+// visualPos returns the visual row and column of p when lines are softly
+// wrapped at width.
+func (ta *textArea) visualPos(p textPos, width int) (int, int) {
+	row := 0
+	for i := 0; i < p.Line; i++ {
+		row += len(rowStarts(ta.lines[i], width))
+	}
+	l := ta.lines[p.Line]
+	starts := rowStarts(l, width)
+	r := len(starts) - 1
+	for starts[r] > p.Col {
+		r--
+	}
+	return row + r, columns(l[starts[r]:p.Col])
+}
+
+// rowStarts returns the index of the first rune of every visual row of a
+// line wrapped at width columns. Rows break between grapheme clusters; wide
+// ones that do not fit at the end of a row go to the next, and a full last
+// row is followed by an empty one for the caret.
+func rowStarts(l []rune, width int) []int {
+	starts := []int{0}
+	if width < 1 {
+		return starts
+	}
+	col := 0
+	s := string(l)
+	for i := 0; s != ""; {
+		g, w := view.FirstGrapheme(s)
+		if col+w > width && i > starts[len(starts)-1] {
+			starts = append(starts, i)
+			col = 0
+		}
+		col += w
+		s = s[len(g):]
+		i += utf8.RuneCountInString(g)
+	}
+	if col >= width {
+		starts = append(starts, len(l))
+	}
+	return starts
+}
+
+// columns returns the number of columns the runes take once they are
+// grouped into grapheme clusters.
+func columns(rs []rune) int {
+	return view.StringWidth(string(rs))
+}
+
+// --------------------
+//   TextArea Store
+// --------------------
+
+type textAreaStore struct {
+	mu sync.Mutex
+	m  map[*dom.Element]*textArea
+}
+
+func newTextAreaStore() *textAreaStore {
+	return &textAreaStore{
+		m: make(map[*dom.Element]*textArea),
+	}
+}
+
+// Get returns the editor of el, reloading it when the element's content was
+// changed from outside the editor.
+func (s *textAreaStore) Get(el *dom.Element) *textArea {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ta, ok := s.m[el]
+	if !ok {
+		ta = newTextArea(el.Content)
+		s.m[el] = ta
+	} else if ta.synced != el.Content {
+		ta.load(el.Content)
+	}
+	return ta
+}
+
+func (s *textAreaStore) Delete(el *dom.Element) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el.Walk(func(el *dom.Element) {
+		delete(s.m, el)
+	})
+}
+
+// --------------------
+//   TextArea Editing
+// --------------------
+
+// handleTextAreaKey applies ev to the textarea el and reports whether the
+// key was consumed.
+func (e *Engine) handleTextAreaKey(el *dom.Element, ev tty.KeyboardEvent) bool {
+	ta := e.textareas.Get(el)
+	width := contentBoundry(el).Width()
+	if width < 1 {
+		width = 1
+	}
+	extend := ev.Modifiers&tty.Modifier_Shift != 0
+	cur := ta.cursor
+
+	switch {
+	case isUndoKey(ev):
+		if !ta.Undo() {
+			return true
+		}
+	case ev.Is(tty.NewByteKey('y'), tty.Modifier_Ctrl):
+		if !ta.Redo() {
+			return true
+		}
+	case ev.Is(tty.SpecialKey_Left):
+		ta.move(ta.left(cur), extend)
+		_, ta.goalCol = ta.visualPos(ta.cursor, width)
+	case ev.Is(tty.SpecialKey_Right):
+		ta.move(ta.right(cur), extend)
+		_, ta.goalCol = ta.visualPos(ta.cursor, width)
+	case ev.Is(tty.SpecialKey_Up):
+		ta.move(ta.vertical(cur, -1, width), extend)
+	case ev.Is(tty.SpecialKey_Down):
+		ta.move(ta.vertical(cur, 1, width), extend)
+	case isHomeKey(ev):
+		ta.move(textPos{Line: cur.Line}, extend)
+		ta.goalCol = 0
+	case isEndKey(ev):
+		ta.move(textPos{Line: cur.Line, Col: len(ta.lines[cur.Line])}, extend)
+		_, ta.goalCol = ta.visualPos(ta.cursor, width)
+	case ev.Is(tty.SpecialKey_Enter):
+		ta.record(editKind_Other)
+		ta.newline()
+	case ev.Is(tty.SpecialKey_Backspace), ev.Is(tty.NewByteKey('h'), tty.Modifier_Ctrl):
+		ta.record(editKind_Delete)
+		ta.backspace()
+	case ev.Is(tty.SpecialKey_Delete):
+		ta.record(editKind_Delete)
+		ta.delete()
+	default:
+		r, ok := printableRune(ev)
+		if !ok {
+			return false
+		}
+		if r == ' ' {
+			ta.lastEdit = editKind_None
+		}
+		ta.record(editKind_Insert)
+		ta.insert(r)
+		_, ta.goalCol = ta.visualPos(ta.cursor, width)
+	}
+
+	e.renderstack.Push(el)
+	if v := ta.Value(); v != el.Content {
+		el.Content = v
+		ta.synced = v
+		e.dispatchEvent(el, dom.EventType_Input, nil)
+	}
+	return true
+}
+
+// isUndoKey reports whether ev undoes the last edit of a textarea: Ctrl+_,
+// which terminals send for Ctrl+/ as well, as in readline and emacs. Ctrl-Z
+// is left to suspend the program.
+func isUndoKey(ev tty.KeyboardEvent) bool {
+	return ev.Is(tty.NewByteKey('_'), tty.Modifier_Ctrl) || ev.Is(tty.NewByteKey('/'), tty.Modifier_Ctrl)
+}
+
+// handleTextAreaPaste inserts text at the caret of el, replacing the
+// selection, as a single undoable edit.
+func (e *Engine) handleTextAreaPaste(el *dom.Element, text string) {
+	ta := e.textareas.Get(el)
+	width := contentBoundry(el).Width()
+	if width < 1 {
+		width = 1
+	}
+
+	ta.record(editKind_Other)
+	ta.deleteSelection()
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			ta.newline()
+		case r == '\t':
+			ta.insert(' ')
+		case isTextRune(r):
+			ta.insert(r)
+		}
+	}
+	ta.lastEdit = editKind_None
+	_, ta.goalCol = ta.visualPos(ta.cursor, width)
+
+	e.renderstack.Push(el)
+	if v := ta.Value(); v != el.Content {
+		el.Content = v
+		ta.synced = v
+		e.dispatchEvent(el, dom.EventType_Input, nil)
+	}
+}
+
+// placeTextAreaCursor moves the caret of el to the cell under screen x, y.
+func (e *Engine) placeTextAreaCursor(el *dom.Element, x, y int) {
+	ta := e.textareas.Get(el)
+	b := contentBoundry(el)
+	if b.Width() < 1 {
+		return
+	}
+	ta.move(ta.posAt(el.State.ScrollY+y-b.FirstY, x-b.FirstX, b.Width()), false)
+	_, ta.goalCol = ta.visualPos(ta.cursor, b.Width())
+}
+
+// --------------------
+//  TextArea Rendering
+// --------------------
+
+var selectionBackground = dom.PaletteColor(24)
+
+// renderTextArea prints the lines of a textarea softly wrapped at the
+// content width, scrolled vertically through State.ScrollY so that the
+// caret stays visible.
+func renderTextArea(elem *dom.Element, ta *textArea, v View) {
+	b := contentBoundry(elem)
+	width, height := b.Width(), b.Height()
+	if width < 1 || height < 1 {
+		return
+	}
+
+	crow, ccol := ta.visualPos(ta.cursor, width)
+	scroll := clamp(elem.State.ScrollY, 0, crow)
+	if crow < scroll {
+		scroll = crow
+	} else if crow >= scroll+height {
+		scroll = crow - height + 1
+	}
+	elem.State.ScrollY = scroll
+
+	style := textStyle(elem.Attrs)
+	row := 0
+	for line, l := range ta.lines {
+		starts := rowStarts(l, width)
+		for i, start := range starts {
+			if row >= scroll+height {
+				break
+			}
+			if row >= scroll {
+				end := len(l)
+				if i+1 < len(starts) {
+					end = starts[i+1]
+				}
+				renderTextAreaRow(elem, ta, v, line, start, end, b.FirstY+row-scroll, style)
+			}
+			row++
+		}
+	}
+
+	if elem.State.Focused {
+		caret, _ := view.FirstGrapheme(string(ta.lines[ta.cursor.Line][ta.cursor.Col:]))
+		if caret == "" {
+			caret = " "
+		}
+		caretStyle := textStyle(elem.Attrs)
+		caretStyle.Foreground, caretStyle.Background = caretForeground, caretBackground
+		if !elem.Attrs.Color.IsDefault() {
+			caretStyle.Background = elem.Attrs.Color
+		}
+		if !elem.Attrs.BackGroundColor.IsDefault() {
+			caretStyle.Foreground = elem.Attrs.BackGroundColor
+		}
+		v.PrintText(b.FirstX+ccol, b.FirstY+crow-scroll, width-ccol, caretStyle, elem.Attrs.ZIndex, elem, caret)
+	}
+}
+
+// renderTextAreaRow prints the runes of a line from start to end on screen
+// row y, one grapheme cluster after another.
+func renderTextAreaRow(elem *dom.Element, ta *textArea, v View, line, start, end, y int, style view.Style) {
+	b := contentBoundry(elem)
+	col := 0
+	for i := start; i < end; {
+		g, w := view.FirstGrapheme(string(ta.lines[line][i:end]))
+		s := style
+		if ta.selected(textPos{Line: line, Col: i}) {
+			s.Background = selectionBackground
+		}
+		v.PrintText(b.FirstX+col, y, b.Width()-col, s, elem.Attrs.ZIndex, elem, g)
+		i += utf8.RuneCountInString(g)
+		col += w
+	}
+}
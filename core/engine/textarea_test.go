@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saman3d/samtui/core/engine/tty"
+	"gotest.tools/v3/assert"
+)
+
+var textAreaTemplate = `<html>
+<head></head>
+<body display="flex" flex-direction="column">
+	<textarea id="msg" writable="true"></textarea>
+</body>
+</html>`
+
+func newTextAreaEngine(t *testing.T, width int) (*Engine, Elementor) {
+	e := newRenderedEngine(t, textAreaTemplate, width, 3)
+	msg := e.GetElementByID("msg")[0]
+	e.Focus(msg.Element())
+	return e, msg
+}
+
+func press(e *Engine, key tty.Key, mod ...tty.Modifiers) {
+	ev := tty.KeyboardEvent{Key: key}
+	for _, m := range mod {
+		ev.Modifiers |= m
+	}
+	e.handleEvent(ev)
+}
+
+func TestTextAreaCtrlZSuspends(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 12)
+	vt := tty.NewVirtualTTY(12, 3)
+	e.TTY = vt
+
+	typeText(e, "draft")
+	_, forwarded := e.handleEvent(tty.KeyboardEvent{Key: tty.NewByteKey('z'), Modifiers: tty.Modifier_Ctrl})
+	assert.Assert(t, !forwarded)
+	assert.Equal(t, vt.Suspends(), 1, "Ctrl-Z suspends while a textarea has the focus")
+	assert.Equal(t, "draft", msg.Value(), "and does not undo")
+
+	press(e, tty.NewByteKey('_'), tty.Modifier_Ctrl)
+	assert.Equal(t, "", msg.Value())
+}
+
+func TestTextAreaEditing(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 12)
+
+	typeText(e, "fix bug")
+	press(e, tty.SpecialKey_Enter)
+	typeText(e, "details")
+	assert.Equal(t, "fix bug\ndetails", msg.Value())
+	assert.Equal(t, msg.Value(), msg.Element().Content)
+
+	press(e, tty.SpecialKey_Up)
+	press(e, tty.SpecialKey_Backspace)
+	assert.Equal(t, "fix bu\ndetails", msg.Value())
+
+	press(e, tty.SpecialKey_End)
+	press(e, tty.SpecialKey_Delete)
+	assert.Equal(t, "fix budetails", msg.Value())
+}
+
+func TestTextAreaUndoRedo(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 6)
+
+	typeText(e, "one two")
+	press(e, tty.SpecialKey_Enter)
+	typeText(e, "three")
+
+	undo := func() { press(e, tty.NewByteKey('_'), tty.Modifier_Ctrl) }
+	redo := func() { press(e, tty.NewByteKey('y'), tty.Modifier_Ctrl) }
+
+	undo()
+	assert.Equal(t, "one two\n", msg.Value())
+	undo()
+	assert.Equal(t, "one two", msg.Value())
+	undo()
+	assert.Equal(t, "one", msg.Value())
+	redo()
+	redo()
+	assert.Equal(t, "one two\n", msg.Value())
+
+	// a new edit drops what was left to redo
+	typeText(e, "x")
+	redo()
+	assert.Equal(t, "one two\nx", msg.Value())
+}
+
+func TestTextAreaSelection(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 6)
+
+	typeText(e, "hello")
+	press(e, tty.SpecialKey_Enter)
+	typeText(e, "world")
+	press(e, tty.SpecialKey_Left, tty.Modifier_Shift)
+	press(e, tty.SpecialKey_Left, tty.Modifier_Shift)
+	press(e, tty.SpecialKey_Up, tty.Modifier_Shift)
+	e.flushRenderStack()
+	assert.Equal(t, selectionBackground, e.View.GetCell(4, 0).Style.Background)
+	assert.Equal(t, DefaultFocusStyle.BackGroundColor, e.View.GetCell(2, 0).Style.Background)
+
+	typeText(e, "p")
+	assert.Equal(t, "help", msg.Value())
+
+	press(e, tty.SpecialKey_Home)
+	press(e, tty.SpecialKey_Right, tty.Modifier_Shift)
+	press(e, tty.SpecialKey_Right, tty.Modifier_Shift)
+	typeText(e, "y")
+	assert.Equal(t, "ylp", msg.Value())
+
+	// moving without shift drops the selection
+	press(e, tty.SpecialKey_Left, tty.Modifier_Shift)
+	press(e, tty.SpecialKey_Right)
+	press(e, tty.SpecialKey_Backspace)
+	assert.Equal(t, "lp", msg.Value())
+}
+
+func TestTextAreaSoftWrap(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 6)
+
+	typeText(e, "abcdefghijkl")
+	press(e, tty.SpecialKey_Enter)
+	typeText(e, "xy")
+	e.flushRenderStack()
+
+	// "abcdef" "ghijkl" "" "xy" scrolled by one row to keep the caret in view
+	assert.Equal(t, 1, msg.Element().State.ScrollY)
+	assert.Equal(t, "ghijkl", rowText(e, 0))
+	assert.Equal(t, "      ", rowText(e, 1))
+	assert.Equal(t, "xy    ", rowText(e, 2))
+
+	press(e, tty.SpecialKey_Up)
+	press(e, tty.SpecialKey_Up)
+	e.flushRenderStack()
+	typeText(e, "_")
+	assert.Equal(t, "abcdefgh_ijkl\nxy", msg.Value())
+}
+
+func TestTextAreaWideWrap(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 5)
+
+	typeText(e, "ab漢字かx")
+	e.flushRenderStack()
+
+	// a wide character that does not fit goes to the next row, and the
+	// caret after a full row to the one after
+	assert.Equal(t, "ab漢 ", rowText(e, 0))
+	assert.Equal(t, "字かx", rowText(e, 1))
+	assert.Equal(t, caretBackground, e.View.GetCell(0, 2).Style.Background)
+
+	press(e, tty.SpecialKey_Up)
+	typeText(e, "_")
+	assert.Equal(t, "ab漢_字かx", msg.Value())
+
+	// both columns of a wide character are on it
+	e.placeTextAreaCursor(msg.Element(), 3, 0)
+	typeText(e, "|")
+	assert.Equal(t, "ab|漢_字かx", msg.Value())
+}
+
+func TestTextAreaGraphemes(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 4)
+
+	e.handleEvent(tty.PasteEvent{Text: "e\u0301👨‍👩‍👧ab🇮🇷"})
+	e.flushRenderStack()
+	// the emoji and the flag take two columns each, not one per rune
+	assert.Equal(t, "e\u0301👨‍👩‍👧a", strings.TrimRight(rowText(e, 0), " "))
+	assert.Equal(t, "b🇮🇷", strings.TrimRight(rowText(e, 1), " "))
+
+	press(e, tty.SpecialKey_Backspace)
+	assert.Equal(t, "e\u0301👨‍👩‍👧ab", msg.Value())
+	press(e, tty.SpecialKey_Home)
+	press(e, tty.SpecialKey_Right)
+	typeText(e, "|")
+	assert.Equal(t, "e\u0301|👨‍👩‍👧ab", msg.Value())
+	press(e, tty.SpecialKey_Delete)
+	assert.Equal(t, "e\u0301|ab", msg.Value())
+	press(e, tty.SpecialKey_Left)
+	press(e, tty.SpecialKey_Backspace)
+	assert.Equal(t, "|ab", msg.Value())
+
+	// a click on the second column of a wide cluster is on it
+	press(e, tty.SpecialKey_End)
+	press(e, tty.SpecialKey_Enter)
+	e.handleEvent(tty.PasteEvent{Text: "👨‍👩‍👧x"})
+	e.placeTextAreaCursor(msg.Element(), 1, 1)
+	typeText(e, "_")
+	assert.Equal(t, "|ab\n_👨‍👩‍👧x", msg.Value())
+}
+
+func TestTextAreaPaste(t *testing.T) {
+	e, msg := newTextAreaEngine(t, 12)
+
+	typeText(e, "a")
+	_, forwarded := e.handleEvent(tty.PasteEvent{Text: "one\r\ntwo\tq"})
+	assert.Assert(t, !forwarded)
+	assert.Equal(t, "aone\ntwo q", msg.Value())
+
+	// the paste is undone in one step
+	press(e, tty.NewByteKey('_'), tty.Modifier_Ctrl)
+	assert.Equal(t, "a", msg.Value())
+}
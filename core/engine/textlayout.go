@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+// --------------------
+//     Text Layout
+// --------------------
+
+const ellipsis = "…"
+
+// textLine is one row of laid out text.
+type textLine struct {
+	text string
+	// last tells whether the line ends a paragraph, which justified text
+	// leaves ragged.
+	last bool
+}
+
+// layoutText breaks text into the lines it shows in a box width columns
+// wide. Line breaks in the text always start a new line. Unless ws keeps
+// them, runs of spaces collapse into one and the spaces around the text and
+// its line breaks are dropped. Unless ws forbids it, lines wrap at the spaces
+// between words, or between clusters when a word is longer than a line.
+func layoutText(text string, width int, ws dom.WhiteSpace) []textLine {
+	collapse := ws == dom.WhiteSpace_Normal || ws == dom.WhiteSpace_NoWrap
+	wrap := ws == dom.WhiteSpace_Normal || ws == dom.WhiteSpace_PreWrap
+
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	if collapse {
+		text = strings.TrimFunc(text, func(r rune) bool { return r == '\n' || isCollapsible(r) })
+	}
+
+	var lines []textLine
+	for _, para := range strings.Split(text, "\n") {
+		if collapse {
+			para = strings.Join(strings.FieldsFunc(para, isCollapsible), " ")
+		} else {
+			para = expandTabs(para)
+		}
+		if wrap && width > 0 {
+			lines = append(lines, wrapParagraph(para, width, collapse)...)
+		} else {
+			lines = append(lines, textLine{text: para})
+		}
+		lines[len(lines)-1].last = true
+	}
+	return lines
+}
+
+// wrapParagraph breaks a paragraph without line breaks into lines. The
+// spaces a line breaks at are dropped when they collapse and otherwise hang
+// at the end of the line.
+func wrapParagraph(para string, width int, collapse bool) []textLine {
+	var (
+		lines []textLine
+		line  strings.Builder
+		col   int
+	)
+	flush := func() {
+		lines = append(lines, textLine{text: line.String()})
+		line.Reset()
+		col = 0
+	}
+
+	for para != "" {
+		var spaces, word string
+		spaces, word, para = nextWord(para)
+		sw, ww := view.StringWidth(spaces), view.StringWidth(word)
+
+		if col > 0 && col+sw+ww > width {
+			if !collapse {
+				line.WriteString(spaces)
+			}
+			flush()
+			spaces, sw = "", 0
+		}
+		line.WriteString(spaces)
+		col += sw
+
+		// a word longer than the line is broken between clusters
+		for ww > width-col {
+			head, tail := view.CutWidth(word, width-col)
+			if col > 0 && view.StringWidth(head) > width-col {
+				flush()
+				continue
+			}
+			line.WriteString(head)
+			flush()
+			word, ww = tail, view.StringWidth(tail)
+		}
+		line.WriteString(word)
+		col += ww
+	}
+	flush()
+	return lines
+}
+
+// nextWord splits s into the spaces before its first word, the word and the
+// rest. Wide clusters, which scripts without spaces are written in, each
+// make a word of their own.
+func nextWord(s string) (spaces, word, rest string) {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	spaces, s = s[:i], s[i:]
+
+	i = 0
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+		cluster, w := view.FirstGrapheme(s[i:])
+		if w > 1 {
+			if i == 0 {
+				i = len(cluster)
+			}
+			break
+		}
+		i += len(cluster)
+	}
+	return spaces, s[:i], s[i:]
+}
+
+// isCollapsible reports whether r is a space that collapses with its
+// neighbours. No-break spaces do not.
+func isCollapsible(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\f' || r == '\v'
+}
+
+// expandTabs replaces the tabs of s with spaces up to the next tab stop.
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for s != "" {
+		cluster, w := view.FirstGrapheme(s)
+		s = s[len(cluster):]
+		if cluster == "\t" {
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteString(cluster)
+		col += w
+	}
+	return b.String()
+}
+
+// alignLine returns the text of l and the column it starts at in a box
+// width columns wide. Justified lines have the spaces between their words
+// widened to fill the box, except the last line of a paragraph.
+func alignLine(l textLine, width int, align dom.TextAlign) (string, int) {
+	free := width - view.StringWidth(l.text)
+	if free <= 0 {
+		return l.text, 0
+	}
+	switch align {
+	case dom.TextAlign_Center:
+		return l.text, free / 2
+	case dom.TextAlign_Right:
+		return l.text, free
+	case dom.TextAlign_Justify:
+		if !l.last {
+			return justify(l.text, free), 0
+		}
+	}
+	return l.text, 0
+}
+
+// justify spreads extra spaces over the gaps between the words of s, the
+// first gaps taking one more when they do not divide evenly.
+func justify(s string, extra int) string {
+	words := strings.Split(s, " ")
+	gaps := len(words) - 1
+	if gaps < 1 {
+		return s
+	}
+	var b strings.Builder
+	for i, word := range words {
+		b.WriteString(word)
+		if i < gaps {
+			n := 1 + extra/gaps
+			if i < extra%gaps {
+				n++
+			}
+			b.WriteString(strings.Repeat(" ", n))
+		}
+	}
+	return b.String()
+}
+
+// ellipsize cuts s to fit width columns with an ellipsis at its end.
+func ellipsize(s string, width int) string {
+	if width < 1 {
+		return ""
+	}
+	var b strings.Builder
+	for col := 0; s != ""; {
+		cluster, w := view.FirstGrapheme(s)
+		if col+w > width-1 {
+			break
+		}
+		b.WriteString(cluster)
+		col += w
+		s = s[len(cluster):]
+	}
+	return strings.TrimRight(b.String(), " ") + ellipsis
+}
+
+// renderText lays the content of elem out in its content box: wrapped,
+// aligned and centered vertically as its attributes ask, and scrolled down
+// by its vertical scroll offset. With an ellipsis text overflow, a line
+// wider than the box and the last line shown when more are hidden end with
+// an ellipsis.
+func renderText(elem *dom.Element, v View) dom.Boundry {
+	boundry := contentBoundry(elem)
+	width, height := boundry.Width(), boundry.Height()
+	if elem.Content == "" || width < 1 || height < 1 {
+		return boundry
+	}
+
+	attrs := elem.Attrs
+	lines := layoutText(elem.Content, width, attrs.WhiteSpace)
+	lines = lines[clamp(elem.State.ScrollY, 0, len(lines)):]
+	shown := lines
+	if len(shown) > height {
+		shown = shown[:height]
+	}
+
+	align := attrs.TextAlign
+	if attrs.HCenter {
+		align = dom.TextAlign_Center
+	}
+	top := 0
+	if attrs.VCenter {
+		top = (height - len(shown)) / 2
+	}
+	style := textStyle(attrs)
+
+	for i, l := range shown {
+		if attrs.TextOverflow == dom.TextOverflow_Ellipsis {
+			if i == len(shown)-1 && len(lines) > len(shown) {
+				l.text = ellipsize(joinLines(lines[i:], attrs.WhiteSpace), width)
+			} else if view.StringWidth(l.text) > width {
+				l.text = ellipsize(l.text, width)
+			}
+		}
+		text, x := alignLine(l, width, align)
+		v.PrintText(boundry.FirstX+x, boundry.FirstY+top+i, width-x, style, attrs.ZIndex, elem, text)
+	}
+	return boundry.ShrinkMask(top+len(shown), dom.PositionMaskTop)
+}
+
+// joinLines puts wrapped lines back together, with the space a collapsed
+// line broke at or between paragraphs.
+func joinLines(lines []textLine, ws dom.WhiteSpace) string {
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 && (lines[i-1].last || ws != dom.WhiteSpace_PreWrap) {
+			b.WriteString(" ")
+		}
+		b.WriteString(l.text)
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"gotest.tools/v3/assert"
+)
+
+func TestLayoutText(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		text  string
+		width int
+		ws    dom.WhiteSpace
+		lines []string
+	}{
+		{"word wrap", "the quick brown fox", 10, dom.WhiteSpace_Normal, []string{"the quick", "brown fox"}},
+		{"collapsed spaces", "\n\t  a   b \t c\n  ", 10, dom.WhiteSpace_Normal, []string{"a b c"}},
+		{"hard breaks", "one\ntwo  \r\n\r\nthree", 10, dom.WhiteSpace_Normal, []string{"one", "two", "", "three"}},
+		{"long word", "abcdefghij xy", 4, dom.WhiteSpace_Normal, []string{"abcd", "efgh", "ij", "xy"}},
+		{"long word after another", "a bcdef", 4, dom.WhiteSpace_Normal, []string{"a", "bcde", "f"}},
+		{"wide characters", "ab漢字ok", 5, dom.WhiteSpace_Normal, []string{"ab漢", "字ok"}},
+		{"nowrap", "a   long line", 4, dom.WhiteSpace_NoWrap, []string{"a long line"}},
+		{"pre", "  a\tb\n c  ", 4, dom.WhiteSpace_Pre, []string{"  a     b", " c  "}},
+		{"pre-wrap", "ab  cd ef", 5, dom.WhiteSpace_PreWrap, []string{"ab  ", "cd ef"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var lines []string
+			for _, l := range layoutText(tc.text, tc.width, tc.ws) {
+				lines = append(lines, l.text)
+			}
+			assert.DeepEqual(t, lines, tc.lines)
+		})
+	}
+}
+
+func TestAlignLine(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		line  textLine
+		align dom.TextAlign
+		text  string
+		x     int
+	}{
+		{"left", textLine{text: "ab cd"}, dom.TextAlign_Left, "ab cd", 0},
+		{"center", textLine{text: "ab cd"}, dom.TextAlign_Center, "ab cd", 2},
+		{"right", textLine{text: "ab cd"}, dom.TextAlign_Right, "ab cd", 5},
+		{"justify", textLine{text: "a b c"}, dom.TextAlign_Justify, "a    b   c", 0},
+		{"justify last line", textLine{text: "a b c d", last: true}, dom.TextAlign_Justify, "a b c d", 0},
+		{"too wide", textLine{text: "abcdefghijkl"}, dom.TextAlign_Right, "abcdefghijkl", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			text, x := alignLine(tc.line, 10, tc.align)
+			assert.Equal(t, text, tc.text)
+			assert.Equal(t, x, tc.x)
+		})
+	}
+}
+
+func TestRenderTextLayout(t *testing.T) {
+	e := newRenderedEngine(t, `<html><head></head>
+<body display="flex" flex-direction="column">
+	<p height="4" text-align="right" vcenter="true">
+		hello
+		world
+	</p>
+	<p height="1" text-overflow="ellipsis">a cell too long to fit</p>
+	<p height="2" text-overflow="ellipsis" text-align="center">one two three four five</p>
+	<p height="1" white-space="nowrap" text-overflow="ellipsis">abcdefghijklmno</p>
+</body>
+</html>`, 12, 8)
+
+	for y, row := range []string{
+		"            ",
+		"       hello",
+		"       world",
+		"            ",
+		"a cell too… ",
+		"  one two   ",
+		"three four… ",
+		"abcdefghijk…",
+	} {
+		assert.Equal(t, rowText(e, y), row, "row %d", y)
+	}
+}
@@ -0,0 +1,149 @@
+package tty
+
+import (
+	"strings"
+
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+// --------------------
+//    Capabilities
+// --------------------
+
+// Capabilities describes what a terminal supports, so that only sequences
+// it understands are sent to it.
+type Capabilities struct {
+	// Term is the terminal type, usually $TERM.
+	Term string
+	// Version is the name and version the terminal reported, if any, e.g.
+	// "kitty(0.35.2)". Terminals that do not answer XTVERSION are named
+	// after their secondary device attributes, e.g. "mintty(30105)".
+	Version string
+
+	Colors             view.ColorDepth
+	Mouse              bool
+	BracketedPaste     bool
+	SynchronizedOutput bool
+	AltScreen          bool
+	Italic             bool
+	Undercurl          bool
+
+	// seqs holds the terminfo strings that drive the terminal, by their
+	// terminfo names. Missing ones fall back to the xterm sequences.
+	seqs map[string]string
+}
+
+// DefaultCapabilities returns the capabilities of a 256 color xterm, which
+// most terminals emulate.
+func DefaultCapabilities() Capabilities {
+	return Capabilities{
+		Term:           "xterm-256color",
+		Colors:         view.ColorDepth_256,
+		Mouse:          true,
+		BracketedPaste: true,
+		AltScreen:      true,
+		Italic:         true,
+	}
+}
+
+// DetectCapabilities works out the capabilities of the terminal from its
+// terminfo entry and the environment: $TERM, $COLORTERM, $NO_COLOR and
+// $TERM_PROGRAM. Without a terminfo entry an xterm is assumed.
+func DetectCapabilities(getenv func(string) string) Capabilities {
+	c := DefaultCapabilities()
+	c.Term = getenv("TERM")
+	if c.Term == "dumb" {
+		c = Capabilities{Term: c.Term}
+	}
+	if ti, err := LoadTerminfo(c.Term, getenv); err == nil {
+		c.applyTerminfo(ti)
+	}
+
+	c.applyTerminal(getenv("TERM_PROGRAM"))
+	c.applyTerminal(strings.TrimPrefix(c.Term, "xterm-"))
+	switch getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		if c.Colors != view.ColorDepth_None {
+			c.Colors = view.ColorDepth_TrueColor
+		}
+	}
+	// https://no-color.org
+	if getenv("NO_COLOR") != "" {
+		c.Colors = view.ColorDepth_None
+	}
+	return c
+}
+
+func (c *Capabilities) applyTerminfo(ti *Terminfo) {
+	colors, ok := ti.Numbers["colors"]
+	switch {
+	case ti.Bools["RGB"] || ti.Bools["Tc"] || colors >= 1<<24:
+		c.Colors = view.ColorDepth_TrueColor
+	case colors >= 256:
+		c.Colors = view.ColorDepth_256
+	case colors >= 16:
+		c.Colors = view.ColorDepth_16
+	case colors >= 8:
+		c.Colors = view.ColorDepth_8
+	case ok || ti.Strings["setaf"] == "":
+		c.Colors = view.ColorDepth_None
+	}
+
+	c.Mouse = ti.Strings["kmous"] != "" || ti.Strings["XM"] != ""
+	// few entries describe bracketed paste; terminals that report the
+	// mouse the way xterm does usually support it too
+	c.BracketedPaste = ti.Strings["BE"] != "" || c.Mouse
+	c.SynchronizedOutput = ti.Strings["Sync"] != ""
+	c.AltScreen = ti.Strings["smcup"] != ""
+	c.Italic = ti.Strings["sitm"] != ""
+	c.Undercurl = ti.Strings["Smulx"] != "" || ti.Bools["Su"]
+
+	c.seqs = make(map[string]string)
+	for _, name := range []string{"clear", "cup", "civis", "cnorm", "smcup", "rmcup"} {
+		if s := ti.Strings[name]; s != "" {
+			c.seqs[name] = s
+		}
+	}
+}
+
+// modernTerminals are terminals known to support synchronized output,
+// undercurl and true color, by the start of their lower-cased names.
+var modernTerminals = []string{"kitty", "ghostty", "wezterm", "foot", "alacritty", "contour", "iterm"}
+
+// applyTerminal turns on what the terminal of the given name is known to
+// support. The name can be $TERM_PROGRAM, $TERM or a version reply.
+func (c *Capabilities) applyTerminal(name string) {
+	name = strings.ToLower(name)
+	for _, prefix := range modernTerminals {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		c.SynchronizedOutput = true
+		c.Undercurl = true
+		if c.Colors != view.ColorDepth_None {
+			c.Colors = view.ColorDepth_TrueColor
+		}
+		return
+	}
+}
+
+// Profile returns what the terminal can draw of a style.
+func (c Capabilities) Profile() view.Profile {
+	return view.Profile{Colors: c.Colors, Italic: c.Italic, Undercurl: c.Undercurl}
+}
+
+// CursorPosition returns the sequence that moves the cursor to the 0-based
+// position x, y.
+func (c Capabilities) CursorPosition(x, y int) string {
+	if cup, ok := c.seqs["cup"]; ok {
+		return Tparm(cup, y, x)
+	}
+	return PositionToEscapeCode(view.Position{X: int64(x + 1), Y: int64(y + 1)})
+}
+
+func (c Capabilities) seq(name, fallback string) string {
+	if s, ok := c.seqs[name]; ok {
+		return stripPadding(s)
+	}
+	return fallback
+}
@@ -18,6 +18,12 @@ const (
 	EventType_Keyboard
 	// EventTypeMouse is the type of a mouse event.
 	EventType_Mouse
+	// EventType_Paste is the type of a paste event.
+	EventType_Paste
+
+	// eventType_Reply is the type of the answers to terminal queries, which
+	// the TTY handles itself.
+	eventType_Reply EventType = -1
 )
 
 // -----------------
@@ -33,6 +39,20 @@ func (e ResizeEvent) Type() EventType {
 	return EventType_Resize
 }
 
+// -----------------
+//    PasteEvent
+// -----------------
+
+// PasteEvent carries text pasted into the terminal while bracketed paste
+// mode is on, delivered at once rather than as keystrokes.
+type PasteEvent struct {
+	Text string
+}
+
+func (e PasteEvent) Type() EventType {
+	return EventType_Paste
+}
+
 // -----------------
 //  KeyaboardEvent
 // -----------------
@@ -40,8 +60,20 @@ func (e ResizeEvent) Type() EventType {
 type KeyboardEvent struct {
 	Key       Key
 	Modifiers Modifiers
+	// Action tells presses from repeats and releases. Terminals report
+	// the latter two only through the kitty keyboard protocol, and only
+	// when the TTY asks for them, see WithKeyReleases.
+	Action KeyAction
 }
 
+type KeyAction int
+
+const (
+	KeyAction_Press KeyAction = iota
+	KeyAction_Repeat
+	KeyAction_Release
+)
+
 func newKeyboardEvent(key Key, mod ...Modifiers) KeyboardEvent {
 	k := KeyboardEvent{
 		Key: key,
@@ -80,10 +112,12 @@ type Key interface {
 type KeyType int
 
 const (
-	// KeyTypeByte is the type of a rune key.
+	// KeyTypeByte is the type of an ASCII key.
 	KeyTypeByte KeyType = iota
 	// KeyTypeSpecial is the type of a special key.
 	KeyTypeSpecial
+	// KeyTypeRune is the type of a key outside ASCII.
+	KeyTypeRune
 )
 
 type ByteKey byte
@@ -97,10 +131,37 @@ func (k ByteKey) Type() KeyType {
 }
 
 func (k ByteKey) Is(key Key) bool {
-	if key.Type() != KeyTypeByte {
-		return false
+	r, ok := KeyRune(key)
+	return ok && rune(k) == r
+}
+
+// RuneKey is a character key. The parser reports ASCII characters as
+// ByteKey and everything else as RuneKey; the two compare equal when they
+// hold the same character.
+type RuneKey rune
+
+func NewRuneKey(r rune) RuneKey {
+	return RuneKey(r)
+}
+
+func (k RuneKey) Type() KeyType {
+	return KeyTypeRune
+}
+
+func (k RuneKey) Is(key Key) bool {
+	r, ok := KeyRune(key)
+	return ok && rune(k) == r
+}
+
+// KeyRune returns the character of a ByteKey or RuneKey.
+func KeyRune(key Key) (rune, bool) {
+	switch k := key.(type) {
+	case ByteKey:
+		return rune(k), true
+	case RuneKey:
+		return rune(k), true
 	}
-	return rune(k) == rune(key.(ByteKey))
+	return 0, false
 }
 
 type SpecialKey string
@@ -120,30 +181,39 @@ func (k SpecialKey) Is(key Key) bool {
 	return string(k) == string(key.(SpecialKey))
 }
 
+// Special keys are identified by one of the sequences terminals send for
+// them. The parser maps the other variants, e.g. "\x1b[H" and "\x1bOH" for
+// Home, to these.
 const (
-	SpecialKey_Escape SpecialKey = "\x1b"
-	SpecialKey_Enter  SpecialKey = "\r"
-	SpecialKey_Up     SpecialKey = "\x1b[A"
-	SpecialKey_Down   SpecialKey = "\x1b[B"
-	SpecialKey_Right  SpecialKey = "\x1b[C"
-	SpecialKey_Left   SpecialKey = "\x1b[D"
-	SpecialKey_Insert SpecialKey = "\x1b[2~"
-	SpecialKey_Delete SpecialKey = "\x1b[3~"
-	SpecialKey_Home   SpecialKey = "\x1b[1~"
-	SpecialKey_End    SpecialKey = "\x1b[4~"
-	SpecialKey_PageUp SpecialKey = "\x1b[5~"
-	SpecialKey_F1     SpecialKey = "\x1bOP"
-	SpecialKey_F2     SpecialKey = "\x1bOQ"
-	SpecialKey_F3     SpecialKey = "\x1bOR"
-	SpecialKey_F4     SpecialKey = "\x1bOS"
-	SpecialKey_F5     SpecialKey = "\x1b[15~"
-	SpecialKey_F6     SpecialKey = "\x1b[17~"
-	SpecialKey_F7     SpecialKey = "\x1b[18~"
-	SpecialKey_F8     SpecialKey = "\x1b[19~"
-	SpecialKey_F9     SpecialKey = "\x1b[20~"
-	SpecialKey_F10    SpecialKey = "\x1b[21~"
-	SpecialKey_F11    SpecialKey = "\x1b[23~"
-	SpecialKey_F12    SpecialKey = "\x1b[24~"
+	SpecialKey_Escape    SpecialKey = "\x1b"
+	SpecialKey_Enter     SpecialKey = "\r"
+	SpecialKey_Tab       SpecialKey = "\t"
+	SpecialKey_Backspace SpecialKey = "\x7f"
+	SpecialKey_Up        SpecialKey = "\x1b[A"
+	SpecialKey_Down      SpecialKey = "\x1b[B"
+	SpecialKey_Right     SpecialKey = "\x1b[C"
+	SpecialKey_Left      SpecialKey = "\x1b[D"
+	SpecialKey_Insert    SpecialKey = "\x1b[2~"
+	SpecialKey_Delete    SpecialKey = "\x1b[3~"
+	SpecialKey_Home      SpecialKey = "\x1b[1~"
+	SpecialKey_End       SpecialKey = "\x1b[4~"
+	SpecialKey_PageUp    SpecialKey = "\x1b[5~"
+	SpecialKey_PageDown  SpecialKey = "\x1b[6~"
+	SpecialKey_F1        SpecialKey = "\x1bOP"
+	SpecialKey_F2        SpecialKey = "\x1bOQ"
+	SpecialKey_F3        SpecialKey = "\x1bOR"
+	SpecialKey_F4        SpecialKey = "\x1bOS"
+	SpecialKey_F5        SpecialKey = "\x1b[15~"
+	SpecialKey_F6        SpecialKey = "\x1b[17~"
+	SpecialKey_F7        SpecialKey = "\x1b[18~"
+	SpecialKey_F8        SpecialKey = "\x1b[19~"
+	SpecialKey_F9        SpecialKey = "\x1b[20~"
+	SpecialKey_F10       SpecialKey = "\x1b[21~"
+	SpecialKey_F11       SpecialKey = "\x1b[23~"
+	SpecialKey_F12       SpecialKey = "\x1b[24~"
+
+	// SpecialKey_ShiftTab is the back-tab sequence sent for Shift+Tab.
+	SpecialKey_ShiftTab SpecialKey = "\x1b[Z"
 )
 
 type Modifiers byte
@@ -153,6 +223,7 @@ const (
 	Modifier_Shift Modifiers = 1 << iota
 	Modifier_Ctrl
 	Modifier_Alt
+	Modifier_Super
 )
 
 // -----------------
@@ -166,10 +237,25 @@ type MouseEvent struct {
 	Y int
 	// Button is the button that was pressed.
 	Button MouseButton
+	// Action tells whether the button was pressed, released or dragged.
+	Action MouseAction
 	// Modifiers is the set of modifiers that were pressed.
 	Modifiers Modifiers
 }
 
+func newMouseEvent(x, y int, button MouseButton, action MouseAction, mod ...Modifiers) MouseEvent {
+	e := MouseEvent{
+		X:      x,
+		Y:      y,
+		Button: button,
+		Action: action,
+	}
+	for _, m := range mod {
+		e.Modifiers |= m
+	}
+	return e
+}
+
 func (e MouseEvent) Type() EventType {
 	return EventType_Mouse
 }
@@ -186,4 +272,25 @@ const (
 	MouseButton_Left
 	MouseButton_Right
 	MouseButton_Middle
+	MouseButton_WheelUp
+	MouseButton_WheelDown
+	MouseButton_WheelLeft
+	MouseButton_WheelRight
+)
+
+// IsWheel reports whether the button is one of the wheel directions.
+func (b MouseButton) IsWheel() bool {
+	return b >= MouseButton_WheelUp && b <= MouseButton_WheelRight
+}
+
+type MouseAction int
+
+const (
+	// MouseAction_Press is reported when a button goes down or the wheel turns.
+	MouseAction_Press MouseAction = iota
+	// MouseAction_Release is reported when a button goes up.
+	MouseAction_Release
+	// MouseAction_Motion is reported when the pointer moves, with or without
+	// a button held down.
+	MouseAction_Motion
 )
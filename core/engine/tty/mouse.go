@@ -0,0 +1,142 @@
+package tty
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// --------------------
+//     Mouse Modes
+// --------------------
+
+type MouseMode uint8
+
+const (
+	// MouseMode_None leaves mouse reporting off.
+	MouseMode_None MouseMode = iota
+	// MouseMode_X10 reports button presses only.
+	MouseMode_X10
+	// MouseMode_Normal reports button presses, releases and the wheel.
+	MouseMode_Normal
+	// MouseMode_ButtonEvent additionally reports motion while a button is held.
+	MouseMode_ButtonEvent
+	// MouseMode_AnyEvent reports every motion, with or without a button held.
+	MouseMode_AnyEvent
+)
+
+var mouseModeSequences = map[MouseMode]string{
+	MouseMode_X10:         "\033[?9h",
+	MouseMode_Normal:      "\033[?1000h",
+	MouseMode_ButtonEvent: "\033[?1000h\033[?1002h",
+	MouseMode_AnyEvent:    "\033[?1000h\033[?1003h",
+}
+
+const (
+	// sgrMouseEnable switches the report encoding to SGR (1006), which has no
+	// coordinate limit and tells releases apart from presses.
+	sgrMouseEnable = "\033[?1006h"
+	// mouseDisable turns every tracking mode and the SGR encoding off.
+	mouseDisable = "\033[?1006l\033[?1003l\033[?1002l\033[?1000l\033[?9l"
+)
+
+// EscapeCode returns the sequence that turns the mode on with SGR encoding.
+func (m MouseMode) EscapeCode() string {
+	seq, ok := mouseModeSequences[m]
+	if !ok {
+		return ""
+	}
+	return seq + sgrMouseEnable
+}
+
+// --------------------
+//   SGR Mouse Parser
+// --------------------
+
+var sgrMousePrefix = []byte("\x1b[<")
+
+const (
+	sgrMouseShift  = 4
+	sgrMouseAlt    = 8
+	sgrMouseCtrl   = 16
+	sgrMouseMotion = 32
+	sgrMouseWheel  = 64
+)
+
+// isSGRMouse reports whether b starts with an SGR mouse report.
+func isSGRMouse(b []byte) bool {
+	return bytes.HasPrefix(b, sgrMousePrefix)
+}
+
+// parseSGRMouse parses a single "ESC [ < b ; x ; y (M|m)" report from the start
+// of b and returns the event together with the number of bytes consumed.
+// Coordinates are converted to be zero based.
+func parseSGRMouse(b []byte) (MouseEvent, int, bool) {
+	if !isSGRMouse(b) {
+		return MouseEvent{}, 0, false
+	}
+
+	end := bytes.IndexAny(b, "Mm")
+	if end < 0 {
+		return MouseEvent{}, 0, false
+	}
+
+	params := bytes.Split(b[len(sgrMousePrefix):end], []byte{';'})
+	if len(params) != 3 {
+		return MouseEvent{}, 0, false
+	}
+
+	var nums [3]int
+	for i, p := range params {
+		n, err := strconv.Atoi(string(p))
+		if err != nil {
+			return MouseEvent{}, 0, false
+		}
+		nums[i] = n
+	}
+
+	code := nums[0]
+	ev := MouseEvent{
+		X:      nums[1] - 1,
+		Y:      nums[2] - 1,
+		Action: MouseAction_Press,
+	}
+
+	if code&sgrMouseShift != 0 {
+		ev.Modifiers |= Modifier_Shift
+	}
+	if code&sgrMouseAlt != 0 {
+		ev.Modifiers |= Modifier_Alt
+	}
+	if code&sgrMouseCtrl != 0 {
+		ev.Modifiers |= Modifier_Ctrl
+	}
+
+	switch {
+	case code&sgrMouseWheel != 0:
+		ev.Button = MouseButton_WheelUp + MouseButton(code&3)
+	case code&sgrMouseMotion != 0:
+		ev.Action = MouseAction_Motion
+		ev.Button = sgrButton(code & 3)
+	default:
+		ev.Button = sgrButton(code & 3)
+	}
+
+	if b[end] == 'm' {
+		ev.Action = MouseAction_Release
+	}
+
+	return ev, end + 1, true
+}
+
+func sgrButton(n int) MouseButton {
+	switch n {
+	case 0:
+		return MouseButton_Left
+	case 1:
+		return MouseButton_Middle
+	case 2:
+		return MouseButton_Right
+	default:
+		return MouseButton_None
+	}
+}
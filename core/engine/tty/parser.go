@@ -1,10 +1,38 @@
 package tty
 
+import "time"
+
 // --------------------
 //    Input Parser
 // --------------------
 
+// InputParser turns the bytes read from a terminal into events. Bytes are
+// fed as they are read; a sequence split across reads yields its event once
+// complete.
 type InputParser interface {
 	// Parse parses the given bytes and returns a slice of events.
 	Parse([]byte) []Event
+	// Pending reports whether the end of the input so far is the start of
+	// an unfinished sequence.
+	Pending() bool
+	// Pasting reports whether a bracketed paste has begun and its end has
+	// not arrived yet.
+	Pasting() bool
+	// Flush gives up waiting for the rest of a pending sequence and returns
+	// its bytes as keys, e.g. a lone ESC as the Escape key.
+	Flush() []Event
 }
+
+// DefaultEscapeTimeout is how long a TTY waits for the rest of an escape
+// sequence before it takes the ESC as a key press.
+const DefaultEscapeTimeout = 50 * time.Millisecond
+
+// DefaultPasteTimeout is how long a TTY waits for more of a bracketed paste
+// before it gives up on its end and delivers the text received so far.
+const DefaultPasteTimeout = time.Second
+
+// pasteStart and pasteEnd enclose text pasted in bracketed paste mode.
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
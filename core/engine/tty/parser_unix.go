@@ -3,82 +3,465 @@
 
 package tty
 
-type unixInputtParser struct{}
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// unixInputtParser turns the byte stream read from a terminal into events.
+// A sequence split across reads is kept until the rest arrives, or until
+// Flush is called when no more input came within the escape timeout.
+type unixInputtParser struct {
+	pending []byte
+}
 
 func newInputParser() InputParser {
 	return &unixInputtParser{}
 }
 
 func (p *unixInputtParser) Parse(b []byte) []Event {
-	return []Event{p.parseKey(b)}
+	p.pending = append(p.pending, b...)
+	events, n := parseInput(p.pending, false)
+	p.pending = append(p.pending[:0], p.pending[n:]...)
+	return events
+}
+
+// Pending reports false while a paste is being received: pasted text may
+// take a while to arrive and must not be cut short by the escape timeout.
+// Pasting reports it instead, for the longer paste timeout.
+func (p *unixInputtParser) Pending() bool {
+	return len(p.pending) > 0 && !p.Pasting()
+}
+
+func (p *unixInputtParser) Pasting() bool {
+	return bytes.HasPrefix(p.pending, pasteStart)
 }
 
-func (p *unixInputtParser) parseKey(b []byte) Event {
-	if len(b) == 1 || b[1] == 0 {
-		if b[0] == 13 {
-			return newKeyboardEvent(NewByteKey(b[0]))
+// Flush returns a paste whose end has not arrived with the text so far.
+func (p *unixInputtParser) Flush() []Event {
+	var events []Event
+	if p.Pasting() {
+		events = []Event{PasteEvent{Text: string(p.pending[len(pasteStart):])}}
+	} else {
+		events, _ = parseInput(p.pending, true)
+	}
+	p.pending = p.pending[:0]
+	return events
+}
+
+// parseInput parses the events at the start of b and returns them with the
+// number of bytes consumed. An incomplete sequence at the end of b is left
+// alone unless final is set, in which case its bytes are taken as keys of
+// their own.
+func parseInput(b []byte, final bool) ([]Event, int) {
+	var events []Event
+	var n int
+	for n < len(b) {
+		ev, size := parseEvent(b[n:])
+		if size == 0 {
+			if !final {
+				break
+			}
+			ev, size = parseIncomplete(b[n:])
 		}
-		if b[0] < 36 {
-			return newKeyboardEvent(NewByteKey(b[0]+96), Modifier_Ctrl)
+		if ev != nil {
+			events = append(events, ev)
 		}
-		return newKeyboardEvent(NewByteKey(b[0]), 0)
+		n += size
+	}
+	return events, n
+}
+
+// parseEvent parses a single event from the start of b. It returns a size
+// of 0 when b holds only the beginning of a sequence or rune.
+func parseEvent(b []byte) (Event, int) {
+	switch {
+	case b[0] == 0x1b:
+		return parseEscape(b)
+	case b[0] < 32 || b[0] == 0x7f:
+		return controlKey(b[0]), 1
+	case b[0] < utf8.RuneSelf:
+		return newKeyboardEvent(NewByteKey(b[0])), 1
+	case !utf8.FullRune(b):
+		return nil, 0
 	}
+	r, size := utf8.DecodeRune(b)
+	return newKeyboardEvent(NewRuneKey(r)), size
+}
 
-	if len(b) == 2 || b[2] == 0 && b[0] == 27 {
-		return newKeyboardEvent(NewByteKey(b[1]), Modifier_Alt)
+// controlKey maps a control character to its key. Legacy terminals send
+// Tab as Ctrl+I, Enter as Ctrl+M and Backspace as DEL, so those are taken as
+// the keys they usually are.
+func controlKey(c byte) KeyboardEvent {
+	switch {
+	case c == '\t':
+		return newKeyboardEvent(SpecialKey_Tab)
+	case c == '\r':
+		return newKeyboardEvent(SpecialKey_Enter)
+	case c == 0x7f:
+		return newKeyboardEvent(SpecialKey_Backspace)
+	case c == 0:
+		return newKeyboardEvent(NewByteKey(' '), Modifier_Ctrl)
+	case c < 27:
+		return newKeyboardEvent(NewByteKey(c+'a'-1), Modifier_Ctrl)
+	default:
+		// Ctrl+\ ] ^ _
+		return newKeyboardEvent(NewByteKey(c+'@'), Modifier_Ctrl)
 	}
+}
 
-	if len(b) == 6 || b[6] == 0 && b[0] == 27 && b[1] == 91 && b[2] == 49 && b[3] == 59 {
-		return newKeyboardEvent(NewSpecialKey(string([]byte{27, 91, b[5]})), byteToModifier(b[4]))
+// parseIncomplete takes the first byte of an unfinished sequence as a key.
+// A lone "ESC P" is Alt+P rather than the start of a terminal reply.
+func parseIncomplete(b []byte) (Event, int) {
+	if len(b) == 2 && b[0] == 0x1b && b[1] == 'P' {
+		return newKeyboardEvent(NewByteKey('P'), Modifier_Alt), 2
 	}
+	if b[0] == 0x1b {
+		return newKeyboardEvent(SpecialKey_Escape), 1
+	}
+	return newKeyboardEvent(NewRuneKey(utf8.RuneError)), 1
+}
 
-	if len(b) == 7 || b[7] == 0 && b[0] == 27 && b[1] == 91 && b[2] == 50 && b[3] == 51 && b[4] == 59 {
-		return newKeyboardEvent(NewSpecialKey(string([]byte{27, 91, 50, 52, b[6]})), byteToModifier(b[5]))
+func parseEscape(b []byte) (Event, int) {
+	if len(b) < 2 {
+		return nil, 0
+	}
+	switch b[1] {
+	case '[':
+		return parseCSI(b)
+	case 'O':
+		if len(b) < 3 {
+			return nil, 0
+		}
+		return newKeyboardEvent(normalizeKey(NewSpecialKey(string(b[:3])))), 3
+	case 'P':
+		if len(b) < 3 || b[2] == '>' {
+			return parseDCS(b)
+		}
+	case 0x1b:
+		return newKeyboardEvent(SpecialKey_Escape), 1
 	}
 
-	return newKeyboardEvent(NewSpecialKey(string(cleanByteArray(b))))
+	// Alt sends the key prefixed with ESC
+	ev, n := parseEvent(b[1:])
+	if n == 0 {
+		return nil, 0
+	}
+	if ke, ok := ev.(KeyboardEvent); ok {
+		ev = ke.SetModifiers(ke.Modifiers | Modifier_Alt)
+	}
+	return ev, n + 1
 }
 
-func (p *unixInputtParser) modifiers(b []byte) Event {
-	var m Modifiers
+// parseCSI parses an "ESC [ params final" sequence.
+func parseCSI(b []byte) (Event, int) {
+	end := -1
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			end = i
+			break
+		}
+		if b[i] < 0x20 || b[i] > 0x3f {
+			// not a CSI sequence after all: ESC [ is Alt+[
+			return newKeyboardEvent(NewByteKey('['), Modifier_Alt), 2
+		}
+	}
+	if end < 0 {
+		return nil, 0
+	}
+	seq := b[:end+1]
+
+	if isSGRMouse(seq) {
+		ev, n, ok := parseSGRMouse(seq)
+		if ok {
+			return ev, n
+		}
+		return nil, len(seq)
+	}
 
-	if len(b) > 1 && b[0] == 0x1b {
-		m |= Modifier_Alt
+	if bytes.Equal(seq, pasteStart) {
+		return parsePaste(b)
+	}
+
+	final := seq[end]
+	body := string(seq[2:end])
+	switch {
+	case strings.HasPrefix(body, "?") && final == 'u':
+		flags, _ := strconv.Atoi(body[1:])
+		return keyboardFlagsReply{Flags: flags}, len(seq)
+	case strings.HasPrefix(body, "?") && final == 'c':
+		return deviceAttributesReply{}, len(seq)
+	case strings.HasPrefix(body, ">") && final == 'c':
+		return parseSecondaryAttributes(body[1:]), len(seq)
+	case final == 'u':
+		return parseKittyKey(body), len(seq)
+	}
+
+	params := strings.Split(body, ";")
+	var mod Modifiers
+	var action KeyAction
+	if len(params) > 1 {
+		mod, action = csiModifier(params[1])
+	}
+	var key Key
+	switch {
+	case final == '~' && len(params) == 3 && params[0] == "27":
+		// xterm modifyOtherKeys: ESC [ 27 ; m ; code ~
+		code, _ := strconv.Atoi(params[2])
+		key, mod = codeKey(code, mod)
+	case final == '~':
+		// ESC [ n ; m ~
+		key = NewSpecialKey("\x1b[" + params[0] + "~")
+	case len(params) == 2 && (params[0] == "1" || params[0] == ""):
+		// ESC [ 1 ; m final
+		key = NewSpecialKey("\x1b[" + string(final))
+	default:
+		key = NewSpecialKey(string(seq))
 	}
+	if key == nil {
+		return nil, len(seq)
+	}
+	if sk, ok := key.(SpecialKey); ok {
+		key = normalizeKey(sk)
+	}
+	return KeyboardEvent{Key: key, Modifiers: mod, Action: action}, len(seq)
+}
 
-	if len(b) == 1 && b[0] < 32 {
-		m |= Modifier_Ctrl
+// parseKittyKey parses the body of a kitty keyboard protocol report,
+// "ESC [ code[:shifted[:base]] ; m[:action] ; text u".
+func parseKittyKey(body string) Event {
+	params := strings.Split(body, ";")
+	codes := strings.Split(params[0], ":")
+	code, _ := strconv.Atoi(codes[0])
+
+	var mod Modifiers
+	var action KeyAction
+	if len(params) > 1 {
+		mod, action = csiModifier(params[1])
+	}
+	if mod&^Modifier_Shift == 0 && len(codes) > 1 && codes[1] != "" {
+		// a shifted character stands for itself
+		if shifted, err := strconv.Atoi(codes[1]); err == nil {
+			code = shifted
+			mod = Modifier_None
+		}
 	}
+	key, mod := codeKey(code, mod)
+	if key == nil {
+		return nil
+	}
+	return KeyboardEvent{Key: key, Modifiers: mod, Action: action}
+}
+
+// Kitty reports the keys without a code point of their own, such as the
+// keypad, media and modifier keys, as code points of a private use area.
+const (
+	kittyFunctionalFirst = 57344
+	kittyFunctionalLast  = 57454
+	kittyKeypad0         = 57399
+	kittyKeypadLeft      = 57417
+)
 
-	return nil
+var kittyKeypad = []rune("0123456789./*-+\r=,")
+
+var kittyKeypadKeys = []SpecialKey{
+	SpecialKey_Left, SpecialKey_Right, SpecialKey_Up, SpecialKey_Down,
+	SpecialKey_PageUp, SpecialKey_PageDown, SpecialKey_Home, SpecialKey_End,
+	SpecialKey_Insert, SpecialKey_Delete,
 }
 
-func cleanByteArray(b []byte) []byte {
-	for i, v := range b {
-		if v == 0 {
-			return b[:i]
+// codeKey returns the key of a code point reported by the kitty protocol or
+// modifyOtherKeys, and mod adjusted for keys that imply a modifier. The key
+// is nil for the functional keys there is no Key for, which must not be
+// taken for text.
+func codeKey(code int, mod Modifiers) (Key, Modifiers) {
+	switch {
+	case code >= kittyKeypad0 && code < kittyKeypad0+len(kittyKeypad):
+		code = int(kittyKeypad[code-kittyKeypad0])
+	case code >= kittyKeypadLeft && code < kittyKeypadLeft+len(kittyKeypadKeys):
+		return kittyKeypadKeys[code-kittyKeypadLeft], mod
+	case code >= kittyFunctionalFirst && code <= kittyFunctionalLast:
+		return nil, mod
+	}
+	switch code {
+	case '\r':
+		return SpecialKey_Enter, mod
+	case '\t':
+		if mod&Modifier_Shift != 0 {
+			return SpecialKey_ShiftTab, mod &^ Modifier_Shift
 		}
+		return SpecialKey_Tab, mod
+	case 0x1b:
+		return SpecialKey_Escape, mod
+	case 0x7f, '\b':
+		return SpecialKey_Backspace, mod
 	}
-	return b
+	if code < utf8.RuneSelf {
+		return NewByteKey(byte(code)), mod
+	}
+	return NewRuneKey(rune(code)), mod
+}
+
+// keyVariants maps the alternative sequences of special keys to the ones
+// the SpecialKey constants use.
+var keyVariants = map[SpecialKey]SpecialKey{
+	"\x1b[H":   SpecialKey_Home,
+	"\x1bOH":   SpecialKey_Home,
+	"\x1b[7~":  SpecialKey_Home,
+	"\x1b[F":   SpecialKey_End,
+	"\x1bOF":   SpecialKey_End,
+	"\x1b[8~":  SpecialKey_End,
+	"\x1bOA":   SpecialKey_Up,
+	"\x1bOB":   SpecialKey_Down,
+	"\x1bOC":   SpecialKey_Right,
+	"\x1bOD":   SpecialKey_Left,
+	"\x1bOM":   SpecialKey_Enter,
+	"\x1b[P":   SpecialKey_F1,
+	"\x1b[Q":   SpecialKey_F2,
+	"\x1b[R":   SpecialKey_F3,
+	"\x1b[S":   SpecialKey_F4,
+	"\x1b[11~": SpecialKey_F1,
+	"\x1b[12~": SpecialKey_F2,
+	"\x1b[13~": SpecialKey_F3,
+	"\x1b[14~": SpecialKey_F4,
+}
+
+func normalizeKey(k SpecialKey) SpecialKey {
+	if v, ok := keyVariants[k]; ok {
+		return v
+	}
+	return k
+}
+
+// --------------------
+//   Terminal Replies
+// --------------------
+
+// keyboardFlagsReply answers a query of the kitty keyboard protocol flags,
+// which only terminals that support the protocol send.
+type keyboardFlagsReply struct {
+	Flags int
+}
+
+func (keyboardFlagsReply) Type() EventType {
+	return eventType_Reply
+}
+
+// deviceAttributesReply answers the primary device attributes query that
+// follows the kitty query: every terminal sends it.
+type deviceAttributesReply struct{}
+
+func (deviceAttributesReply) Type() EventType {
+	return eventType_Reply
+}
+
+// secondaryAttributesReply answers the secondary device attributes query,
+// "ESC [ > id ; version ; rom c", with a number that stands for the type
+// of the terminal and its version.
+type secondaryAttributesReply struct {
+	ID      int
+	Version int
+}
+
+func (secondaryAttributesReply) Type() EventType {
+	return eventType_Reply
+}
+
+// secondaryTerminalIDs are the terminals with an id of their own in the
+// secondary device attributes. Most others claim to be a VT100 or VT220.
+var secondaryTerminalIDs = map[int]string{
+	41: "xterm",
+	77: "mintty",
+	83: "screen",
+	84: "tmux",
+	85: "rxvt-unicode",
+}
+
+// terminal returns the name and version the reply stands for, or "" when
+// its id does not tell the terminal.
+func (r secondaryAttributesReply) terminal() string {
+	name, ok := secondaryTerminalIDs[r.ID]
+	if !ok {
+		return ""
+	}
+	return name + "(" + strconv.Itoa(r.Version) + ")"
+}
+
+func parseSecondaryAttributes(body string) secondaryAttributesReply {
+	var r secondaryAttributesReply
+	params := strings.Split(body, ";")
+	r.ID, _ = strconv.Atoi(params[0])
+	if len(params) > 1 {
+		r.Version, _ = strconv.Atoi(params[1])
+	}
+	return r
+}
+
+// terminalVersionReply answers the XTVERSION query with the name and
+// version of the terminal.
+type terminalVersionReply struct {
+	Version string
+}
+
+func (terminalVersionReply) Type() EventType {
+	return eventType_Reply
+}
+
+// parseDCS parses a device control string, "ESC P body ESC \", of which
+// only the XTVERSION reply, "ESC P > | version ESC \", is of interest.
+func parseDCS(b []byte) (Event, int) {
+	end := bytes.Index(b, []byte("\x1b\\"))
+	if end < 0 {
+		return nil, 0
+	}
+	body := b[2:end]
+	if bytes.HasPrefix(body, []byte(">|")) {
+		return terminalVersionReply{Version: string(body[2:])}, end + 2
+	}
+	return nil, end + 2
+}
+
+// parsePaste parses a bracketed paste, "ESC [ 200 ~ text ESC [ 201 ~".
+func parsePaste(b []byte) (Event, int) {
+	text := b[len(pasteStart):]
+	end := bytes.Index(text, pasteEnd)
+	if end < 0 {
+		return nil, 0
+	}
+	return PasteEvent{Text: string(text[:end])}, len(pasteStart) + end + len(pasteEnd)
 }
 
-func byteToModifier(n byte) Modifiers {
+// csiModifier decodes the xterm modifier parameter, which is one more than
+// a bit set of shift (1), alt (2), ctrl (4) and super (8). The kitty
+// protocol appends the key action after a colon.
+func csiModifier(param string) (Modifiers, KeyAction) {
+	var action KeyAction
+	if i := strings.IndexByte(param, ':'); i >= 0 {
+		a, _ := strconv.Atoi(param[i+1:])
+		if a > 1 {
+			action = KeyAction(a - 1)
+		}
+		param = param[:i]
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil || n < 1 {
+		return Modifier_None, action
+	}
+	n--
 	var md Modifiers
-	switch n {
-	case 50:
-		md = Modifier_Shift
-	case 51:
-		md = Modifier_Alt
-	case 52:
-		md = Modifier_Alt | Modifier_Shift
-	case 53:
-		md = Modifier_Ctrl
-	case 54:
-		md = Modifier_Ctrl | Modifier_Shift
-	case 55:
-		md = Modifier_Ctrl | Modifier_Alt
-	case 56:
-		md = Modifier_Ctrl | Modifier_Shift | Modifier_Alt
-	}
-	return md
+	if n&1 != 0 {
+		md |= Modifier_Shift
+	}
+	if n&2 != 0 {
+		md |= Modifier_Alt
+	}
+	if n&4 != 0 {
+		md |= Modifier_Ctrl
+	}
+	if n&8 != 0 {
+		md |= Modifier_Super
+	}
+	return md, action
 }
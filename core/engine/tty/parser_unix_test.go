@@ -0,0 +1,438 @@
+//go:build unix
+
+package tty
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+type mouseParseTestSuite struct {
+	name     string
+	input    string
+	expected []Event
+}
+
+var mouseParseTestSuites = []mouseParseTestSuite{
+	{
+		name:  "left press",
+		input: "\x1b[<0;10;5M",
+		expected: []Event{
+			newMouseEvent(9, 4, MouseButton_Left, MouseAction_Press),
+		},
+	},
+	{
+		name:  "right release",
+		input: "\x1b[<2;1;1m",
+		expected: []Event{
+			newMouseEvent(0, 0, MouseButton_Right, MouseAction_Release),
+		},
+	},
+	{
+		name:  "drag with ctrl",
+		input: "\x1b[<48;120;45M",
+		expected: []Event{
+			newMouseEvent(119, 44, MouseButton_Left, MouseAction_Motion, Modifier_Ctrl),
+		},
+	},
+	{
+		name:  "motion without button",
+		input: "\x1b[<35;3;4M",
+		expected: []Event{
+			newMouseEvent(2, 3, MouseButton_None, MouseAction_Motion),
+		},
+	},
+	{
+		name:  "wheel with shift and alt",
+		input: "\x1b[<77;7;8M",
+		expected: []Event{
+			newMouseEvent(6, 7, MouseButton_WheelDown, MouseAction_Press, Modifier_Shift, Modifier_Alt),
+		},
+	},
+	{
+		name:  "several reports in one read",
+		input: "\x1b[<64;1;1M\x1b[<1;2;2M\x1b[<1;2;2m",
+		expected: []Event{
+			newMouseEvent(0, 0, MouseButton_WheelUp, MouseAction_Press),
+			newMouseEvent(1, 1, MouseButton_Middle, MouseAction_Press),
+			newMouseEvent(1, 1, MouseButton_Middle, MouseAction_Release),
+		},
+	},
+}
+
+func TestParseMouse(t *testing.T) {
+	p := newInputParser()
+	for _, suite := range mouseParseTestSuites {
+		t.Run(suite.name, func(t *testing.T) {
+			assert.DeepEqual(t, suite.expected, p.Parse([]byte(suite.input)))
+		})
+	}
+}
+
+type keyParseTestSuite struct {
+	name string
+	// reads holds the input as it arrives, one read per entry
+	reads    []string
+	expected []Event
+	// pending is what Flush returns after the last read
+	pending []Event
+}
+
+var keyParseTestSuites = []keyParseTestSuite{
+	{
+		name:  "typing in one read",
+		reads: []string{"hi\r"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('h')),
+			newKeyboardEvent(NewByteKey('i')),
+			newKeyboardEvent(SpecialKey_Enter),
+		},
+	},
+	{
+		name:  "utf-8 runes",
+		reads: []string{"é世"},
+		expected: []Event{
+			newKeyboardEvent(NewRuneKey('é')),
+			newKeyboardEvent(NewRuneKey('世')),
+		},
+	},
+	{
+		name:  "rune split across reads",
+		reads: []string{"\xe4\xb8", "\x96!"},
+		expected: []Event{
+			newKeyboardEvent(NewRuneKey('世')),
+			newKeyboardEvent(NewByteKey('!')),
+		},
+	},
+	{
+		name:  "sequence split across reads",
+		reads: []string{"a\x1b[", "1;5", "Ab"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('a')),
+			newKeyboardEvent(SpecialKey_Up, Modifier_Ctrl),
+			newKeyboardEvent(NewByteKey('b')),
+		},
+	},
+	{
+		name:  "ctrl and alt",
+		reads: []string{"\x03\x1bb\x1b\x7f"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('c'), Modifier_Ctrl),
+			newKeyboardEvent(NewByteKey('b'), Modifier_Alt),
+			newKeyboardEvent(SpecialKey_Backspace, Modifier_Alt),
+		},
+	},
+	{
+		name:  "special keys",
+		reads: []string{"\x1b[3~\x1b[5;2~\x1bOP\x1b[Z"},
+		expected: []Event{
+			newKeyboardEvent(SpecialKey_Delete),
+			newKeyboardEvent(SpecialKey_PageUp, Modifier_Shift),
+			newKeyboardEvent(SpecialKey_F1),
+			newKeyboardEvent(SpecialKey_ShiftTab),
+		},
+	},
+	{
+		name:  "keys and mouse mixed",
+		reads: []string{"x\x1b[<0;2;3My"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('x')),
+			newMouseEvent(1, 2, MouseButton_Left, MouseAction_Press),
+			newKeyboardEvent(NewByteKey('y')),
+		},
+	},
+	{
+		name:     "lone escape waits for the timeout",
+		reads:    []string{"\x1b"},
+		expected: nil,
+		pending: []Event{
+			newKeyboardEvent(SpecialKey_Escape),
+		},
+	},
+	{
+		name:  "escape then escape sequence",
+		reads: []string{"\x1b\x1b[A"},
+		expected: []Event{
+			newKeyboardEvent(SpecialKey_Escape),
+			newKeyboardEvent(SpecialKey_Up),
+		},
+	},
+	{
+		name:     "unfinished sequence is flushed byte by byte",
+		reads:    []string{"\x1b[1;"},
+		expected: nil,
+		pending: []Event{
+			newKeyboardEvent(SpecialKey_Escape),
+			newKeyboardEvent(NewByteKey('[')),
+			newKeyboardEvent(NewByteKey('1')),
+			newKeyboardEvent(NewByteKey(';')),
+		},
+	},
+}
+
+var keyProtocolTestSuites = []keyParseTestSuite{
+	{
+		name:  "legacy control characters",
+		reads: []string{"\t\r\x7f\x08\x1c\x00"},
+		expected: []Event{
+			newKeyboardEvent(SpecialKey_Tab),
+			newKeyboardEvent(SpecialKey_Enter),
+			newKeyboardEvent(SpecialKey_Backspace),
+			newKeyboardEvent(NewByteKey('h'), Modifier_Ctrl),
+			newKeyboardEvent(NewByteKey('\\'), Modifier_Ctrl),
+			newKeyboardEvent(NewByteKey(' '), Modifier_Ctrl),
+		},
+	},
+	{
+		name:  "home and end variants",
+		reads: []string{"\x1b[H\x1bOH\x1b[7~\x1b[1~\x1b[F\x1bOF\x1b[1;5H"},
+		expected: []Event{
+			newKeyboardEvent(SpecialKey_Home),
+			newKeyboardEvent(SpecialKey_Home),
+			newKeyboardEvent(SpecialKey_Home),
+			newKeyboardEvent(SpecialKey_Home),
+			newKeyboardEvent(SpecialKey_End),
+			newKeyboardEvent(SpecialKey_End),
+			newKeyboardEvent(SpecialKey_Home, Modifier_Ctrl),
+		},
+	},
+	{
+		name:  "application cursor keys and function keys",
+		reads: []string{"\x1bOA\x1b[6~\x1b[1;2P\x1b[11~"},
+		expected: []Event{
+			newKeyboardEvent(SpecialKey_Up),
+			newKeyboardEvent(SpecialKey_PageDown),
+			newKeyboardEvent(SpecialKey_F1, Modifier_Shift),
+			newKeyboardEvent(SpecialKey_F1),
+		},
+	},
+	{
+		name:  "kitty tells ctrl+i from tab",
+		reads: []string{"\x1b[105;5u\x1b[9u\x1b[9;2u\x1b[13;5u\x1b[27u"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('i'), Modifier_Ctrl),
+			newKeyboardEvent(SpecialKey_Tab),
+			newKeyboardEvent(SpecialKey_ShiftTab),
+			newKeyboardEvent(SpecialKey_Enter, Modifier_Ctrl),
+			newKeyboardEvent(SpecialKey_Escape),
+		},
+	},
+	{
+		name:  "kitty shifted keys, keypad and unicode",
+		reads: []string{"\x1b[97:65;2u\x1b[57401u\x1b[233;3u"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('A')),
+			newKeyboardEvent(NewByteKey('2')),
+			newKeyboardEvent(NewRuneKey('é'), Modifier_Alt),
+		},
+	},
+	{
+		name:  "kitty functional keys are not text",
+		reads: []string{"x\x1b[57419u\x1b[57426;5u\x1b[57416u\x1b[57441;2u\x1b[57428u\x1b[57376u\x1b[27;1;57399~y"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('x')),
+			newKeyboardEvent(SpecialKey_Up),
+			newKeyboardEvent(SpecialKey_Delete, Modifier_Ctrl),
+			newKeyboardEvent(NewByteKey(',')),
+			newKeyboardEvent(NewByteKey('0')),
+			newKeyboardEvent(NewByteKey('y')),
+		},
+	},
+	{
+		name:  "kitty repeat and release",
+		reads: []string{"\x1b[97;1:2u\x1b[97;5:3u\x1b[1;1:3A"},
+		expected: []Event{
+			KeyboardEvent{Key: NewByteKey('a'), Action: KeyAction_Repeat},
+			KeyboardEvent{Key: NewByteKey('a'), Modifiers: Modifier_Ctrl, Action: KeyAction_Release},
+			KeyboardEvent{Key: SpecialKey_Up, Action: KeyAction_Release},
+		},
+	},
+	{
+		name:  "modifyOtherKeys",
+		reads: []string{"\x1b[27;5;105~\x1b[27;2;9~\x1b[27;9;13~"},
+		expected: []Event{
+			newKeyboardEvent(NewByteKey('i'), Modifier_Ctrl),
+			newKeyboardEvent(SpecialKey_ShiftTab),
+			newKeyboardEvent(SpecialKey_Enter, Modifier_Super),
+		},
+	},
+	{
+		name:  "terminal replies",
+		reads: []string{"\x1b[?1u\x1b[?62;22c"},
+		expected: []Event{
+			keyboardFlagsReply{Flags: 1},
+			deviceAttributesReply{},
+		},
+	},
+	{
+		name:  "secondary device attributes",
+		reads: []string{"\x1b[>77;30105;0c\x1b[>1c\x1b[?62c"},
+		expected: []Event{
+			secondaryAttributesReply{ID: 77, Version: 30105},
+			secondaryAttributesReply{ID: 1},
+			deviceAttributesReply{},
+		},
+	},
+	{
+		name:  "terminal version reply",
+		reads: []string{"\x1bP>|kitty(0.35", ".2)\x1b\\\x1bPx\x1bP"},
+		expected: []Event{
+			terminalVersionReply{Version: "kitty(0.35.2)"},
+			newKeyboardEvent(NewByteKey('P'), Modifier_Alt),
+			newKeyboardEvent(NewByteKey('x')),
+		},
+		pending: []Event{
+			newKeyboardEvent(NewByteKey('P'), Modifier_Alt),
+		},
+	},
+}
+
+func TestParseKeys(t *testing.T) {
+	for _, suite := range append(keyParseTestSuites, keyProtocolTestSuites...) {
+		t.Run(suite.name, func(t *testing.T) {
+			p := newInputParser()
+			var got []Event
+			for _, r := range suite.reads {
+				got = append(got, p.Parse([]byte(r))...)
+			}
+			assert.DeepEqual(t, suite.expected, got)
+			assert.Equal(t, p.Pending(), suite.pending != nil)
+			assert.DeepEqual(t, suite.pending, p.Flush())
+			assert.Assert(t, !p.Pending())
+		})
+	}
+}
+
+func TestRuneKeyIs(t *testing.T) {
+	assert.Assert(t, NewRuneKey('a').Is(NewByteKey('a')))
+	assert.Assert(t, NewByteKey('a').Is(NewRuneKey('a')))
+	assert.Assert(t, !NewRuneKey('é').Is(NewByteKey('e')))
+	assert.Assert(t, !NewRuneKey('[').Is(NewSpecialKey("[")))
+}
+
+func TestParsePaste(t *testing.T) {
+	p := newInputParser()
+	got := p.Parse([]byte("a\x1b[200~q\x1b[A"))
+	assert.DeepEqual(t, []Event{newKeyboardEvent(NewByteKey('a'))}, got)
+	assert.Assert(t, !p.Pending(), "an unfinished paste does not time out")
+	assert.Assert(t, p.Pasting())
+
+	got = p.Parse([]byte("é\r\nx\x1b[201~b"))
+	assert.DeepEqual(t, []Event{
+		PasteEvent{Text: "q\x1b[Aé\r\nx"},
+		newKeyboardEvent(NewByteKey('b')),
+	}, got)
+
+	p.Parse([]byte("\x1b[200~cut"))
+	assert.DeepEqual(t, []Event{PasteEvent{Text: "cut"}}, p.Flush())
+}
+
+// pipeReader is an InputReader that reads what is written to w and
+// records what the TTY writes.
+type pipeReader struct {
+	*io.PipeReader
+	w *io.PipeWriter
+
+	mu  sync.Mutex
+	out []byte
+}
+
+func newPipeReader() *pipeReader {
+	r, w := io.Pipe()
+	return &pipeReader{PipeReader: r, w: w}
+}
+
+func (r *pipeReader) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.out = append(r.out, b...)
+	return len(b), nil
+}
+
+func (r *pipeReader) output() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return string(r.out)
+}
+
+func (r *pipeReader) Fd() uintptr    { return 0 }
+func (r *pipeReader) MakeRaw() error { return nil }
+func (r *pipeReader) Restore() error { return nil }
+
+func TestWatchPasteTimeout(t *testing.T) {
+	in := newPipeReader()
+	tt := &TTY{
+		inpreader:    in,
+		inpparser:    newInputParser(),
+		esctimeout:   DefaultEscapeTimeout,
+		pastetimeout: 20 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan Event)
+	go tt.Watch(ctx, ch)
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+
+	// the terminal never sends the end of the paste
+	in.w.Write([]byte("\x1b[200~cut"))
+	assert.DeepEqual(t, next(), PasteEvent{Text: "cut"})
+
+	in.w.Write([]byte("x"))
+	assert.DeepEqual(t, next(), newKeyboardEvent(NewByteKey('x')))
+}
+
+func TestWatchKeyboardReply(t *testing.T) {
+	in := newPipeReader()
+	tt := &TTY{
+		inpreader: in,
+		inpparser: newInputParser(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tt.Watch(ctx, make(chan Event))
+
+	// the reply picks the keyboard protocol on the Watch goroutine while
+	// the terminal is written to from another
+	in.w.Write([]byte("\x1b[?62c"))
+	tt.Restore()
+	tt.Write([]byte("frame"))
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(in.output(), modifyOtherKeysEnable) {
+		if time.Now().After(deadline) {
+			t.Fatalf("keyboard enhancements were never enabled: %q", in.output())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Assert(t, strings.Contains(in.output(), "frame"))
+}
+
+func TestSecondaryAttributesReply(t *testing.T) {
+	tt := &TTY{inpreader: newPipeReader()}
+	tt.handleReply(secondaryAttributesReply{ID: 1, Version: 10})
+	assert.Equal(t, tt.Capabilities().Version, "", "a VT220 could be anything")
+	tt.handleReply(secondaryAttributesReply{ID: 84, Version: 3})
+	assert.Equal(t, tt.Capabilities().Version, "tmux(3)")
+
+	// a version reply names the terminal better
+	tt = &TTY{inpreader: newPipeReader()}
+	tt.handleReply(terminalVersionReply{Version: "kitty(0.35.2)"})
+	tt.handleReply(secondaryAttributesReply{ID: 41, Version: 388})
+	assert.Equal(t, tt.Capabilities().Version, "kitty(0.35.2)")
+}
@@ -11,4 +11,8 @@ import (
 type InputReader interface {
 	io.ReadWriteCloser
 	Fd() uintptr
+	// MakeRaw puts the terminal into raw mode.
+	MakeRaw() error
+	// Restore puts the terminal back into the mode it was opened in.
+	Restore() error
 }
@@ -38,18 +38,26 @@ func newInputReader() (InputReader, error) {
 
 	// 2. Save the current state of the TTY
 	// and set the terminal to raw mode.
-	tty.saved, err = term.MakeRaw(int(tty.fd))
+	tty.saved, err = term.GetState(int(tty.fd))
 	if err != nil {
 		return nil, err
 	}
-	tty.saved, err = term.GetState(int(tty.fd))
-	if err != nil {
+	if err = tty.MakeRaw(); err != nil {
 		return nil, err
 	}
 
 	return &tty, nil
 }
 
+func (tty *unixReader) MakeRaw() error {
+	_, err := term.MakeRaw(int(tty.fd))
+	return err
+}
+
+func (tty *unixReader) Restore() error {
+	return term.Restore(int(tty.fd), tty.saved)
+}
+
 func (tty *unixReader) Read(p []byte) (int, error) {
 	return tty.f.Read(p)
 }
@@ -59,7 +67,7 @@ func (tty *unixReader) Write(p []byte) (int, error) {
 }
 
 func (tty *unixReader) Close() error {
-	term.Restore(int(tty.fd), tty.saved)
+	tty.Restore()
 	return tty.f.Close()
 }
 
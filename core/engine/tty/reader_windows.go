@@ -31,6 +31,14 @@ func (tty *windowsReader) Read(p []byte) (int, error) {
 	return tty.f.Read(p)
 }
 
+func (tty *windowsReader) MakeRaw() error {
+	return nil
+}
+
+func (tty *windowsReader) Restore() error {
+	return nil
+}
+
 func (tty *windowsReader) Close() error {
 	return tty.f.Close()
 }
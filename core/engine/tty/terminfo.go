@@ -0,0 +1,495 @@
+package tty
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrTerminfoNotFound = errors.New("terminfo entry not found")
+	ErrTerminfoInvalid  = errors.New("invalid terminfo entry")
+)
+
+// --------------------
+//      Terminfo
+// --------------------
+
+// Terminfo is an entry of the compiled terminfo database. Capabilities are
+// keyed by their short terminfo names, e.g. "colors", "cup" or "setaf";
+// extended capabilities such as "RGB", "Smulx" or "Sync" by their own.
+type Terminfo struct {
+	Names   []string
+	Bools   map[string]bool
+	Numbers map[string]int
+	Strings map[string]string
+}
+
+// The standard capabilities are stored by position. Only the positions the
+// TTY makes use of are listed.
+var (
+	terminfoNumbers = map[int]string{
+		0:  "cols",
+		2:  "lines",
+		13: "colors",
+		14: "pairs",
+	}
+	terminfoStrings = map[int]string{
+		5:   "clear",
+		6:   "el",
+		10:  "cup",
+		13:  "civis",
+		16:  "cnorm",
+		26:  "blink",
+		27:  "bold",
+		28:  "smcup",
+		30:  "dim",
+		34:  "rev",
+		36:  "smul",
+		39:  "sgr0",
+		40:  "rmcup",
+		311: "sitm",
+		355: "kmous",
+		359: "setaf",
+		360: "setab",
+	}
+)
+
+const (
+	terminfoMagic   = 0o432
+	terminfoMagic32 = 0o1036
+)
+
+// LoadTerminfo finds the entry of term in the terminfo database, searching
+// $TERMINFO, ~/.terminfo, $TERMINFO_DIRS and the usual system directories.
+func LoadTerminfo(term string, getenv func(string) string) (*Terminfo, error) {
+	if term == "" || strings.ContainsAny(term, "/\\") {
+		return nil, fmt.Errorf("%w: %q", ErrTerminfoNotFound, term)
+	}
+	for _, dir := range terminfoDirs(getenv) {
+		for _, sub := range []string{term[:1], fmt.Sprintf("%x", term[0])} {
+			data, err := os.ReadFile(filepath.Join(dir, sub, term))
+			if err == nil {
+				return ParseTerminfo(data)
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrTerminfoNotFound, term)
+}
+
+func terminfoDirs(getenv func(string) string) []string {
+	system := []string{"/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo", "/usr/lib/terminfo"}
+	var dirs []string
+	if d := getenv("TERMINFO"); d != "" {
+		dirs = append(dirs, d)
+	}
+	if home := getenv("HOME"); home != "" {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	if list := getenv("TERMINFO_DIRS"); list != "" {
+		for _, d := range strings.Split(list, ":") {
+			if d == "" {
+				// an empty entry stands for the system directories
+				dirs = append(dirs, system...)
+				continue
+			}
+			dirs = append(dirs, d)
+		}
+	}
+	return append(dirs, system...)
+}
+
+// ParseTerminfo decodes a compiled terminfo entry in either the legacy or
+// the 32-bit number format, extended capabilities included.
+func ParseTerminfo(data []byte) (*Terminfo, error) {
+	r := &terminfoReader{data: data}
+	magic := r.short()
+	numSize := 2
+	switch magic {
+	case terminfoMagic:
+	case terminfoMagic32:
+		numSize = 4
+	default:
+		return nil, fmt.Errorf("%w: bad magic %#o", ErrTerminfoInvalid, magic)
+	}
+	namesSize, boolCount, numCount, strCount, tableSize := r.count(), r.count(), r.count(), r.count(), r.count()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	ti := &Terminfo{
+		Bools:   make(map[string]bool),
+		Numbers: make(map[string]int),
+		Strings: make(map[string]string),
+	}
+	names := strings.TrimRight(string(r.bytes(namesSize)), "\x00")
+	ti.Names = strings.Split(names, "|")
+
+	r.bytes(boolCount)
+	r.align()
+	for i := 0; i < numCount; i++ {
+		n := r.number(numSize)
+		if name, ok := terminfoNumbers[i]; ok && n >= 0 {
+			ti.Numbers[name] = n
+		}
+	}
+	offsets := make([]int, strCount)
+	for i := range offsets {
+		offsets[i] = r.short()
+	}
+	table := r.bytes(tableSize)
+	for i, off := range offsets {
+		if name, ok := terminfoStrings[i]; ok {
+			if s, ok := cString(table, off); ok {
+				ti.Strings[name] = s
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	r.align()
+	if r.pos < len(data) {
+		if err := ti.parseExtended(r, numSize); err != nil {
+			return nil, err
+		}
+	}
+	return ti, nil
+}
+
+// parseExtended decodes the user-defined capabilities that follow the
+// standard ones. Their names are stored after their string values.
+func (ti *Terminfo) parseExtended(r *terminfoReader, numSize int) error {
+	boolCount, numCount, strCount, _, tableSize := r.count(), r.count(), r.count(), r.short(), r.count()
+	if r.err != nil {
+		return r.err
+	}
+	bools := r.bytes(boolCount)
+	r.align()
+	nums := make([]int, numCount)
+	for i := range nums {
+		nums[i] = r.number(numSize)
+	}
+	strOffsets := make([]int, strCount)
+	for i := range strOffsets {
+		strOffsets[i] = r.short()
+	}
+	nameOffsets := make([]int, boolCount+numCount+strCount)
+	for i := range nameOffsets {
+		nameOffsets[i] = r.short()
+	}
+	table := r.bytes(tableSize)
+	if r.err != nil {
+		return r.err
+	}
+
+	// names are offsets into the part of the table after the last value
+	var namesStart int
+	for _, off := range strOffsets {
+		if s, ok := cString(table, off); ok && off+len(s)+1 > namesStart {
+			namesStart = off + len(s) + 1
+		}
+	}
+	name := func(i int) string {
+		s, _ := cString(table, namesStart+nameOffsets[i])
+		return s
+	}
+	for i, b := range bools {
+		if b == 1 {
+			ti.Bools[name(i)] = true
+		}
+	}
+	for i, n := range nums {
+		if n >= 0 {
+			ti.Numbers[name(boolCount+i)] = n
+		}
+	}
+	for i, off := range strOffsets {
+		if s, ok := cString(table, off); ok {
+			ti.Strings[name(boolCount+numCount+i)] = s
+		}
+	}
+	return nil
+}
+
+func cString(table []byte, off int) (string, bool) {
+	if off < 0 || off >= len(table) {
+		return "", false
+	}
+	end := off
+	for end < len(table) && table[end] != 0 {
+		end++
+	}
+	return string(table[off:end]), true
+}
+
+type terminfoReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *terminfoReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("%w: truncated", ErrTerminfoInvalid)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *terminfoReader) short() int {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return int(int16(binary.LittleEndian.Uint16(b)))
+}
+
+// count reads the size of a section of the entry. Sizes are stored as
+// signed shorts; a negative one, or one larger than the whole entry, can
+// only come from a corrupt file.
+func (r *terminfoReader) count() int {
+	n := r.short()
+	if r.err == nil && (n < 0 || n > len(r.data)) {
+		r.err = fmt.Errorf("%w: bad size %d", ErrTerminfoInvalid, n)
+	}
+	if r.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (r *terminfoReader) number(size int) int {
+	if size == 2 {
+		return r.short()
+	}
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int(int32(binary.LittleEndian.Uint32(b)))
+}
+
+func (r *terminfoReader) align() {
+	if r.pos%2 == 1 && r.pos < len(r.data) {
+		r.pos++
+	}
+}
+
+// --------------------
+//   Parameterization
+// --------------------
+
+// Tparm fills the parameters of the terminfo string s, e.g. the row and
+// column of "cup". It supports the integer subset of the terminfo
+// language: %p, %i, %d, %c, %{n}, %'c', arithmetic, comparisons and %? %t
+// %e %; conditionals. Padding such as $<5> is left out, as terminals
+// today need none.
+func Tparm(s string, args ...int) string {
+	var params [9]int
+	copy(params[:], args)
+	var out strings.Builder
+	var stack []int
+	push := func(n int) { stack = append(stack, n) }
+	pop := func() int {
+		if len(stack) == 0 {
+			return 0
+		}
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return n
+	}
+	bool2int := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	for i := 0; i < len(s); i++ {
+		if n := paddingLen(s[i:]); n > 0 {
+			i += n - 1
+			continue
+		}
+		if s[i] != '%' || i+1 >= len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		// skip printf flags and widths, e.g. %2d or %:-3d
+		j := i
+		if s[j] == ':' {
+			for j++; j < len(s) && strings.IndexByte("-+# ", s[j]) >= 0; j++ {
+			}
+		}
+		for ; j < len(s) && strings.IndexByte("0123456789.", s[j]) >= 0; j++ {
+		}
+		if j > i && j < len(s) && strings.IndexByte("doxXs", s[j]) >= 0 {
+			i = j
+		}
+		switch c := s[i]; c {
+		case '%':
+			out.WriteByte('%')
+		case 'd', 'o', 'x', 'X', 's':
+			n := pop()
+			switch c {
+			case 'o':
+				out.WriteString(strconv.FormatInt(int64(n), 8))
+			case 'x':
+				out.WriteString(strconv.FormatInt(int64(n), 16))
+			case 'X':
+				out.WriteString(strings.ToUpper(strconv.FormatInt(int64(n), 16)))
+			default:
+				out.WriteString(strconv.Itoa(n))
+			}
+		case 'c':
+			out.WriteByte(byte(pop()))
+		case 'p':
+			if i+1 < len(s) && s[i+1] >= '1' && s[i+1] <= '9' {
+				i++
+				push(params[s[i]-'1'])
+			}
+		case 'i':
+			params[0]++
+			params[1]++
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return out.String()
+			}
+			n, _ := strconv.Atoi(s[i+1 : i+end])
+			push(n)
+			i += end
+		case '\'':
+			if i+2 < len(s) {
+				push(int(s[i+1]))
+				i += 2
+			}
+		case 'l':
+			pop()
+			push(0)
+		case '+', '-', '*', '/', 'm', '&', '|', '^', '=', '>', '<', 'A', 'O':
+			b, a := pop(), pop()
+			switch c {
+			case '+':
+				push(a + b)
+			case '-':
+				push(a - b)
+			case '*':
+				push(a * b)
+			case '/':
+				if b != 0 {
+					push(a / b)
+				} else {
+					push(0)
+				}
+			case 'm':
+				if b != 0 {
+					push(a % b)
+				} else {
+					push(0)
+				}
+			case '&':
+				push(a & b)
+			case '|':
+				push(a | b)
+			case '^':
+				push(a ^ b)
+			case '=':
+				push(bool2int(a == b))
+			case '>':
+				push(bool2int(a > b))
+			case '<':
+				push(bool2int(a < b))
+			case 'A':
+				push(bool2int(a != 0 && b != 0))
+			case 'O':
+				push(bool2int(a != 0 || b != 0))
+			}
+		case '!':
+			push(bool2int(pop() == 0))
+		case '~':
+			push(^pop())
+		case '?', ';':
+		case 't':
+			if pop() == 0 {
+				i = skipConditional(s, i+1, true)
+			}
+		case 'e':
+			// reached the end of a taken branch
+			i = skipConditional(s, i+1, false)
+		}
+	}
+	return out.String()
+}
+
+// paddingLen returns the length of the delay, e.g. "$<5>" or "$<2.5*/>",
+// that s starts with, or 0.
+func paddingLen(s string) int {
+	if !strings.HasPrefix(s, "$<") {
+		return 0
+	}
+	i := 2
+	for i < len(s) && strings.IndexByte("0123456789.", s[i]) >= 0 {
+		i++
+	}
+	if i == 2 {
+		return 0
+	}
+	for i < len(s) && (s[i] == '*' || s[i] == '/') {
+		i++
+	}
+	if i < len(s) && s[i] == '>' {
+		return i + 1
+	}
+	return 0
+}
+
+// stripPadding returns s without the delays paddingLen recognizes.
+func stripPadding(s string) string {
+	if !strings.Contains(s, "$<") {
+		return s
+	}
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if n := paddingLen(s[i:]); n > 0 {
+			i += n - 1
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+// skipConditional skips from i past the matching %e, when elseToo is set,
+// or %; of the current conditional and returns the index of its last byte.
+func skipConditional(s string, i int, elseToo bool) int {
+	depth := 0
+	for ; i < len(s)-1; i++ {
+		if s[i] != '%' {
+			continue
+		}
+		i++
+		switch s[i] {
+		case '?':
+			depth++
+		case ';':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case 'e':
+			if depth == 0 && elseToo {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,188 @@
+package tty_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+// testEnv returns a getenv that only knows env and finds terminfo entries
+// in testdata.
+func testEnv(env map[string]string) func(string) string {
+	return func(key string) string {
+		if key == "TERMINFO" {
+			return "testdata/terminfo"
+		}
+		return env[key]
+	}
+}
+
+func TestLoadTerminfo(t *testing.T) {
+	mono, err := tty.LoadTerminfo("samtui-mono", testEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mono.Names[0] != "samtui-mono" || mono.Numbers["cols"] != 80 || mono.Numbers["lines"] != 24 {
+		t.Errorf("unexpected entry %+v", mono)
+	}
+	if _, ok := mono.Numbers["colors"]; ok {
+		t.Error("colors is not set for a monochrome terminal")
+	}
+
+	// the legacy format with 16-bit numbers
+	color, err := tty.LoadTerminfo("samtui-8color", testEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if color.Numbers["colors"] != 8 || color.Strings["setaf"] != "\033[3%p1%dm" || color.Strings["cup"] != "\033[%i%p1%d;%p2%dH" {
+		t.Errorf("unexpected entry %+v", color)
+	}
+
+	// the format with 32-bit numbers and extended capabilities
+	direct, err := tty.LoadTerminfo("samtui-direct", testEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if direct.Numbers["colors"] != 1<<24 || !direct.Bools["RGB"] || direct.Strings["Smulx"] != "\033[4:%p1%dm" || direct.Strings["kmous"] != "\033[M" {
+		t.Errorf("unexpected entry %+v", direct)
+	}
+
+	if _, err := tty.LoadTerminfo("samtui-missing", testEnv(nil)); !errors.Is(err, tty.ErrTerminfoNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, data := range [][]byte{
+		{0x1a, 0x01, 0x10},
+		// a negative number of strings
+		{0x1a, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00},
+		// names larger than the whole entry
+		{0x1a, 0x01, 0xff, 0x7f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		// a negative number of extended booleans
+		{0x1a, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'x', 0x00,
+			0xfe, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if _, err := tty.ParseTerminfo(data); !errors.Is(err, tty.ErrTerminfoInvalid) {
+			t.Errorf("% x: unexpected error %v", data, err)
+		}
+	}
+}
+
+func TestSystemTerminfo(t *testing.T) {
+	ti, err := tty.LoadTerminfo("xterm-256color", os.Getenv)
+	if err != nil {
+		t.Skip("xterm-256color is not installed:", err)
+	}
+	if ti.Numbers["colors"] != 256 || ti.Strings["smcup"] == "" || ti.Strings["kmous"] == "" {
+		t.Errorf("unexpected entry %+v", ti)
+	}
+	if got := tty.Tparm(ti.Strings["cup"], 4, 9); got != "\033[5;10H" {
+		t.Errorf("cup gives %q", got)
+	}
+}
+
+func TestTparm(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cap  string
+		args []int
+		want string
+	}{
+		{"cursor position", "\033[%i%p1%d;%p2%dH", []int{0, 9}, "\033[1;10H"},
+		{"character", "\033Y%p1%' '%+%c%p2%' '%+%c", []int{1, 2}, "\033Y!\""},
+		{"xterm setaf low", "\033[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m", []int{3}, "\033[33m"},
+		{"xterm setaf bright", "\033[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m", []int{12}, "\033[94m"},
+		{"xterm setaf 256", "\033[%?%p1%{8}%<%t3%p1%d%e%p1%{16}%<%t9%p1%{8}%-%d%e38;5;%p1%d%;m", []int{200}, "\033[38;5;200m"},
+		{"synchronized output", "\033[?2026%?%p1%{1}%-%tl%eh%;", []int{1}, "\033[?2026h"},
+		{"percent", "100%%", nil, "100%"},
+		{"padding", "\033[?5h$<100/>\033[?5l", nil, "\033[?5h\033[?5l"},
+		{"padding after parameters", "\033[%i%p1%d;%p2%dH$<5>", []int{0, 0}, "\033[1;1H"},
+		{"no padding", "$<x>$", nil, "$<x>$"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tty.Tparm(tc.cap, tc.args...); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		want tty.Capabilities
+	}{
+		{
+			name: "monochrome",
+			env:  map[string]string{"TERM": "samtui-mono"},
+			want: tty.Capabilities{Term: "samtui-mono", Colors: view.ColorDepth_None},
+		},
+		{
+			name: "eight colors",
+			env:  map[string]string{"TERM": "samtui-8color"},
+			want: tty.Capabilities{Term: "samtui-8color", Colors: view.ColorDepth_8},
+		},
+		{
+			name: "extended capabilities",
+			env:  map[string]string{"TERM": "samtui-direct"},
+			want: tty.Capabilities{
+				Term: "samtui-direct", Colors: view.ColorDepth_TrueColor, Mouse: true, BracketedPaste: true,
+				SynchronizedOutput: true, AltScreen: true, Italic: true, Undercurl: true,
+			},
+		},
+		{
+			name: "COLORTERM",
+			env:  map[string]string{"TERM": "samtui-8color", "COLORTERM": "truecolor"},
+			want: tty.Capabilities{Term: "samtui-8color", Colors: view.ColorDepth_TrueColor},
+		},
+		{
+			name: "NO_COLOR",
+			env:  map[string]string{"TERM": "samtui-direct", "NO_COLOR": "1", "COLORTERM": "truecolor"},
+			want: tty.Capabilities{
+				Term: "samtui-direct", Colors: view.ColorDepth_None, Mouse: true, BracketedPaste: true,
+				SynchronizedOutput: true, AltScreen: true, Italic: true, Undercurl: true,
+			},
+		},
+		{
+			name: "known terminal program",
+			env:  map[string]string{"TERM": "samtui-8color", "TERM_PROGRAM": "WezTerm"},
+			want: tty.Capabilities{Term: "samtui-8color", Colors: view.ColorDepth_TrueColor, SynchronizedOutput: true, Undercurl: true},
+		},
+		{
+			name: "unknown terminal is an xterm",
+			env:  map[string]string{"TERM": "samtui-missing"},
+			want: tty.Capabilities{
+				Term: "samtui-missing", Colors: view.ColorDepth_256, Mouse: true, BracketedPaste: true,
+				AltScreen: true, Italic: true,
+			},
+		},
+		{
+			name: "dumb",
+			env:  map[string]string{"TERM": "dumb"},
+			want: tty.Capabilities{Term: "dumb"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tty.DetectCapabilities(testEnv(tc.env))
+			// the terminfo strings are checked through the sequences they
+			// make, see TestCapabilitiesCursorPosition
+			if got.Term != tc.want.Term || got.Colors != tc.want.Colors || got.Mouse != tc.want.Mouse ||
+				got.BracketedPaste != tc.want.BracketedPaste || got.SynchronizedOutput != tc.want.SynchronizedOutput ||
+				got.AltScreen != tc.want.AltScreen || got.Italic != tc.want.Italic || got.Undercurl != tc.want.Undercurl {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesCursorPosition(t *testing.T) {
+	mono := tty.DetectCapabilities(testEnv(map[string]string{"TERM": "samtui-mono"}))
+	if got := mono.CursorPosition(9, 4); got != "\033[5;10H" {
+		t.Errorf("terminfo cup gives %q", got)
+	}
+	if got := tty.DefaultCapabilities().CursorPosition(0, 0); got != "\033[1;1H" {
+		t.Errorf("default cup gives %q", got)
+	}
+}
@@ -2,11 +2,12 @@ package tty
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	"golang.org/x/term"
 )
@@ -17,14 +18,77 @@ type TTY struct {
 	wg        *sync.WaitGroup
 	cursor    [2]int
 	sigwinch  chan os.Signal
+	mouse     MouseMode
+	sync      *bool
+	altscreen bool
+	caps      Capabilities
+	capsMu    sync.Mutex
+	capsFixed bool
+	sigtstp   chan os.Signal
+	closeOnce sync.Once
+	closeErr  error
+
+	// outMu serializes the output to the terminal, so that what the Watch
+	// loop writes never lands in the middle of a frame, and guards the
+	// terminal state that output depends on.
+	outMu sync.Mutex
+
+	esctimeout   time.Duration
+	pastetimeout time.Duration
+	keyboard     keyboardProtocol
+	keyflags     int
 }
 
-func NewTTY() (*TTY, error) {
+const (
+	altScreenEnable  = "\033[?1049h"
+	altScreenDisable = "\033[?1049l"
+	cursorShow       = "\033[?25h"
+	cursorHide       = "\033[?25l"
+	styleReset       = "\033[0m"
+	pasteEnable      = "\033[?2004h"
+	pasteDisable     = "\033[?2004l"
+
+	// terminalQuery asks for the kitty keyboard flags, the terminal
+	// version and the secondary device attributes, followed by the primary
+	// device attributes so that a terminal that knows none of them answers
+	// too.
+	terminalQuery          = "\033[?u\033[>q\033[>c\033[c"
+	kittyKeyboardPop       = "\033[<u"
+	modifyOtherKeysEnable  = "\033[>4;2m"
+	modifyOtherKeysDisable = "\033[>4m"
+)
+
+// keyboardProtocol is the way the terminal reports modified keys.
+type keyboardProtocol int
+
+const (
+	keyboardProtocol_Unknown keyboardProtocol = iota
+	keyboardProtocol_Kitty
+	keyboardProtocol_ModifyOtherKeys
+)
+
+// kitty keyboard protocol flags
+const (
+	kittyFlag_Disambiguate = 1
+	kittyFlag_EventTypes   = 2
+)
+
+func NewTTY(opts ...TTYOpt) (*TTY, error) {
 	var err error
 	tty := &TTY{
 		wg:       &sync.WaitGroup{},
 		cursor:   [2]int{},
 		sigwinch: make(chan os.Signal),
+		sigtstp:  make(chan os.Signal, 1),
+
+		caps:         DetectCapabilities(os.Getenv),
+		esctimeout:   DefaultEscapeTimeout,
+		pastetimeout: DefaultPasteTimeout,
+		keyflags:     kittyFlag_Disambiguate,
+	}
+
+	for _, opt := range opts {
+		opt(tty)
 	}
 
 	tty.inpreader, err = newInputReader()
@@ -33,14 +97,98 @@ func NewTTY() (*TTY, error) {
 	}
 
 	signal.Notify(tty.sigwinch, syscall.SIGWINCH)
+	signal.Notify(tty.sigtstp, syscall.SIGTSTP)
 
 	tty.inpparser = newInputParser()
 
 	tty.DisableCursor()
+	if tty.caps.BracketedPaste {
+		tty.inpreader.Write([]byte(pasteEnable))
+	}
+	tty.inpreader.Write([]byte(terminalQuery))
+
+	if tty.mouse != MouseMode_None {
+		tty.EnableMouse(tty.mouse)
+	}
 
 	return tty, nil
 }
 
+// --------------------
+//      TTY Opts
+// --------------------
+
+type TTYOpt func(*TTY)
+
+// WithMouse turns mouse reporting on in the given mode as soon as the TTY
+// is opened.
+func WithMouse(mode MouseMode) TTYOpt {
+	return func(t *TTY) {
+		t.mouse = mode
+	}
+}
+
+// WithEscapeTimeout sets how long to wait for the rest of an escape
+// sequence before a lone ESC is taken as the Escape key.
+func WithEscapeTimeout(d time.Duration) TTYOpt {
+	return func(t *TTY) {
+		t.esctimeout = d
+	}
+}
+
+// WithPasteTimeout sets how long to wait for more of a bracketed paste
+// before the text received so far is delivered as the paste. It guards
+// against a terminal that never sends the end of the paste.
+func WithPasteTimeout(d time.Duration) TTYOpt {
+	return func(t *TTY) {
+		t.pastetimeout = d
+	}
+}
+
+// WithKeyReleases asks terminals that speak the kitty keyboard protocol to
+// report key repeats and releases as well as presses.
+func WithKeyReleases() TTYOpt {
+	return func(t *TTY) {
+		t.keyflags |= kittyFlag_EventTypes
+	}
+}
+
+// WithCapabilities sets what the terminal supports instead of detecting
+// it, see DetectCapabilities.
+func WithCapabilities(c Capabilities) TTYOpt {
+	return func(t *TTY) {
+		t.caps = c
+		t.capsFixed = true
+	}
+}
+
+// WithSynchronizedOutput overrides the detection of synchronized output
+// support.
+func WithSynchronizedOutput(on bool) TTYOpt {
+	return func(t *TTY) {
+		t.sync = &on
+	}
+}
+
+// Capabilities returns what the terminal supports. Detection goes on while
+// the TTY is watched, as the terminal answers the queries sent to it.
+func (t *TTY) Capabilities() Capabilities {
+	t.capsMu.Lock()
+	defer t.capsMu.Unlock()
+	c := t.caps
+	if t.sync != nil {
+		c.SynchronizedOutput = *t.sync
+	}
+	return c
+}
+
+// SynchronizedOutput reports whether the terminal is known to support
+// synchronized output (DEC private mode 2026), which lets a frame be shown
+// at once rather than as it is written.
+func (t *TTY) SynchronizedOutput() bool {
+	return t.Capabilities().SynchronizedOutput
+}
+
 func (t *TTY) Wait() {
 	t.wg.Wait()
 }
@@ -48,6 +196,7 @@ func (t *TTY) Wait() {
 func (t *TTY) Watch(ctx context.Context, echan chan Event) error {
 	inpchan := make(chan []byte)
 	go t.readChan(inpchan)
+	var esctimer <-chan time.Time
 	for {
 		select {
 		case <-ctx.Done():
@@ -59,9 +208,41 @@ func (t *TTY) Watch(ctx context.Context, echan chan Event) error {
 
 			// 2. Send input events to the channel.
 			for _, i := range input {
+				if i.Type() == eventType_Reply {
+					t.handleReply(i)
+					continue
+				}
 				echan <- i
 			}
 
+			// 3. Wait a little for the rest of an unfinished sequence, or
+			// longer for the rest of a paste.
+			esctimer = nil
+			switch {
+			case t.inpparser.Pending():
+				esctimer = time.After(t.esctimeout)
+			case t.inpparser.Pasting():
+				esctimer = time.After(t.pastetimeout)
+			}
+
+		case <-esctimer:
+			esctimer = nil
+			for _, i := range t.inpparser.Flush() {
+				if i.Type() != eventType_Reply {
+					echan <- i
+				}
+			}
+
+		case <-t.sigtstp:
+			// stopped from outside, e.g. with kill -TSTP; the screen is
+			// redrawn by the resize that follows
+			t.Suspend()
+			w, h, _ := t.WindowSize()
+			echan <- ResizeEvent{
+				Width:  w,
+				Height: h,
+			}
+
 		case <-t.sigwinch:
 			w, h, _ := t.WindowSize()
 			echan <- ResizeEvent{
@@ -75,15 +256,15 @@ func (t *TTY) Watch(ctx context.Context, echan chan Event) error {
 func (t *TTY) readChan(ch chan []byte) {
 	for {
 		// 1. Read input from the TTY.
-		b := make([]byte, 10)
-		_, err := t.inpreader.Read(b)
+		b := make([]byte, 256)
+		n, err := t.inpreader.Read(b)
 		if err != nil {
-			fmt.Println(err)
-			// return
+			// the TTY was closed
+			return
 		}
 
 		// 2. Send input to the channel.
-		ch <- b
+		ch <- b[:n]
 	}
 
 }
@@ -94,37 +275,258 @@ func (t *TTY) Start(ctx context.Context, ch chan Event) error {
 	return nil
 }
 
+// Close restores the terminal, see Restore, and closes it. Calls after the
+// first do nothing.
 func (t *TTY) Close() error {
-	err := t.inpreader.Close()
-	if err != nil {
-		return err
-	}
-	return nil
+	t.closeOnce.Do(func() {
+		signal.Stop(t.sigtstp)
+		t.Restore()
+		t.closeErr = t.inpreader.Close()
+	})
+	return t.closeErr
 }
 
 func (t *TTY) WindowSize() (int, int, error) {
-	t.inpreader, _ = newInputReader()
 	return term.GetSize(int(t.inpreader.Fd()))
 }
 
+// --------------------
+//   Terminal State
+// --------------------
+
+// handleReply picks the keyboard protocol from the answers to the query
+// sent when the TTY was opened: the kitty protocol when the terminal knows
+// it, xterm's modifyOtherKeys otherwise. A version reply, or failing that
+// the secondary device attributes, adds to the detected capabilities.
+func (t *TTY) handleReply(ev Event) {
+	if v, ok := ev.(terminalVersionReply); ok {
+		t.capsMu.Lock()
+		if !t.capsFixed {
+			t.caps.Version = v.Version
+			t.caps.applyTerminal(v.Version)
+		}
+		t.capsMu.Unlock()
+		return
+	}
+	if da, ok := ev.(secondaryAttributesReply); ok {
+		t.capsMu.Lock()
+		// the version reply comes first when there is one, and names the
+		// terminal better
+		if name := da.terminal(); !t.capsFixed && t.caps.Version == "" && name != "" {
+			t.caps.Version = name
+			t.caps.applyTerminal(name)
+		}
+		t.capsMu.Unlock()
+		return
+	}
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	if t.keyboard != keyboardProtocol_Unknown {
+		return
+	}
+	switch ev.(type) {
+	case keyboardFlagsReply:
+		t.keyboard = keyboardProtocol_Kitty
+	case deviceAttributesReply:
+		t.keyboard = keyboardProtocol_ModifyOtherKeys
+	default:
+		return
+	}
+	t.inpreader.Write([]byte(t.keyboardEnable()))
+}
+
+// write writes b to the terminal under outMu.
+func (t *TTY) write(b []byte) (int, error) {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	return t.inpreader.Write(b)
+}
+
+func (t *TTY) keyboardEnable() string {
+	switch t.keyboard {
+	case keyboardProtocol_Kitty:
+		return "\033[>" + strconv.Itoa(t.keyflags) + "u"
+	case keyboardProtocol_ModifyOtherKeys:
+		return modifyOtherKeysEnable
+	}
+	return ""
+}
+
+func (t *TTY) keyboardDisable() string {
+	switch t.keyboard {
+	case keyboardProtocol_Kitty:
+		return kittyKeyboardPop
+	case keyboardProtocol_ModifyOtherKeys:
+		return modifyOtherKeysDisable
+	}
+	return ""
+}
+
+// EnterAltScreen switches to the alternate screen, leaving the shell's
+// screen untouched until the TTY is restored.
+// Terminals without one are left as they are.
+func (t *TTY) EnterAltScreen() {
+	c := t.Capabilities()
+	if !c.AltScreen {
+		return
+	}
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	t.altscreen = true
+	t.inpreader.Write([]byte(c.seq("smcup", altScreenEnable)))
+}
+
+// ExitAltScreen switches back to the main screen.
+func (t *TTY) ExitAltScreen() {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	if !t.altscreen {
+		return
+	}
+	t.altscreen = false
+	t.inpreader.Write([]byte(t.Capabilities().seq("rmcup", altScreenDisable)))
+}
+
+// Restore hands the terminal back in the state it was opened in: mouse
+// reporting, bracketed paste and keyboard enhancements off, default style,
+// cursor shown, main screen and cooked mode.
+// The settings of the TTY are kept so that Resume can apply them again.
+func (t *TTY) Restore() error {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	return t.restore()
+}
+
+func (t *TTY) restore() error {
+	c := t.Capabilities()
+	seq := t.keyboardDisable() + styleReset + c.seq("cnorm", cursorShow)
+	if c.BracketedPaste {
+		seq += pasteDisable
+	}
+	if t.mouse != MouseMode_None {
+		seq = mouseDisable + seq
+	}
+	if t.altscreen {
+		seq += c.seq("rmcup", altScreenDisable)
+	}
+	t.inpreader.Write([]byte(seq))
+	return t.inpreader.Restore()
+}
+
+// Resume sets the terminal up again after Restore.
+func (t *TTY) Resume() error {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	return t.resume()
+}
+
+func (t *TTY) resume() error {
+	if err := t.inpreader.MakeRaw(); err != nil {
+		return err
+	}
+	c := t.Capabilities()
+	var seq string
+	if t.altscreen {
+		seq += c.seq("smcup", altScreenEnable)
+	}
+	seq += c.seq("civis", cursorHide) + t.keyboardEnable()
+	if c.BracketedPaste {
+		seq += pasteEnable
+	}
+	if t.mouse != MouseMode_None {
+		seq += t.mouse.EscapeCode()
+	}
+	_, err := t.inpreader.Write([]byte(seq))
+	return err
+}
+
+// Suspend restores the terminal and stops the process, as Ctrl-Z does in a
+// cooked terminal. It returns once the process is continued, with the
+// terminal set up again. Callers redraw the whole screen afterwards.
+// Nothing is written to the terminal in the meantime.
+func (t *TTY) Suspend() error {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	if err := t.restore(); err != nil {
+		return err
+	}
+
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+	defer signal.Stop(cont)
+
+	// let SIGTSTP take its default action while it is raised
+	signal.Reset(syscall.SIGTSTP)
+	err := syscall.Kill(syscall.Getpid(), syscall.SIGTSTP)
+	if err == nil {
+		<-cont
+	}
+	signal.Notify(t.sigtstp, syscall.SIGTSTP)
+	if err != nil {
+		t.resume()
+		return err
+	}
+	return t.resume()
+}
+
+// Clear clears the screen and moves the cursor to its top left corner.
 func (t *TTY) Clear() {
-	t.inpreader.Write([]byte("\033[2J"))
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	t.setCursor(0, 0)
+	t.inpreader.Write([]byte(t.Capabilities().seq("clear", "\033[H\033[2J")))
 }
 
 func (t *TTY) WritePos(x, y int, b []byte) (int, error) {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
 	if t.cursor[0] != x || t.cursor[1] != y {
-		t.SetPos(x, y)
+		t.setPos(x, y)
 	}
 	return t.inpreader.Write(b)
 }
 
 func (t *TTY) Write(b []byte) (int, error) {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
 	t.setCursor(t.cursor[0]+len(b), t.cursor[1])
 	return t.inpreader.Write(b)
 }
 
 func (t *TTY) DisableCursor() {
-	t.inpreader.Write([]byte("\033[?25l"))
+	t.write([]byte(t.Capabilities().seq("civis", cursorHide)))
+}
+
+func (t *TTY) EnableCursor() {
+	t.write([]byte(t.Capabilities().seq("cnorm", cursorShow)))
+}
+
+// EnableMouse switches mouse reporting to the given mode using the SGR
+// encoding. Passing MouseMode_None turns reporting off. Terminals without
+// mouse support are left as they are.
+func (t *TTY) EnableMouse(mode MouseMode) {
+	if mode == MouseMode_None {
+		t.DisableMouse()
+		return
+	}
+	if !t.Capabilities().Mouse {
+		return
+	}
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	if t.mouse != MouseMode_None && t.mouse != mode {
+		t.inpreader.Write([]byte(mouseDisable))
+	}
+	t.mouse = mode
+	t.inpreader.Write([]byte(mode.EscapeCode()))
+}
+
+// DisableMouse turns every mouse reporting mode off.
+func (t *TTY) DisableMouse() {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	t.mouse = MouseMode_None
+	t.inpreader.Write([]byte(mouseDisable))
 }
 
 func (t *TTY) setCursor(x, y int) {
@@ -133,6 +535,12 @@ func (t *TTY) setCursor(x, y int) {
 }
 
 func (t *TTY) SetPos(x, y int) {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	t.setPos(x, y)
+}
+
+func (t *TTY) setPos(x, y int) {
 	t.cursor[0], t.cursor[1] = x, y
-	t.inpreader.Write([]byte("\033[" + fmt.Sprint(y+1) + ";" + fmt.Sprint(x+1) + "H"))
+	t.inpreader.Write([]byte(t.Capabilities().CursorPosition(x, y)))
 }
@@ -3,10 +3,13 @@ package tty_test
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/saman3d/samtui/core/dom"
 	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
 )
 
 // // devTty is an implementation of the Tty API based upon /dev/tty.
@@ -163,40 +166,49 @@ import (
 func TestDevTty(t *testing.T) {
 	ch := make(chan tty.Event, 1)
 
-	tt, err := tty.NewTTY()
-	if err != nil {
-		t.Fatal(err)
-		return
-	}
-	fmt.Println("tty", tt)
+	tt := tty.NewVirtualTTY(80, 24)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	err = tt.Start(ctx, ch)
-	if err != nil {
-		t.Fatal(err)
-		return
-	}
+	go tt.Watch(ctx, ch)
+
+	tt.SendKey(tty.KeyboardEvent{Key: tty.SpecialKey_F12})
+	tt.SendMouse(tty.MouseEvent{X: 3, Y: 4, Button: tty.MouseButton_Left})
+	tt.SendBytes([]byte("\x1b[<0;2;3m"))
+	tt.Resize(100, 30)
 
-	go func() {
-		for e := range ch {
-			fmt.Println("event", e, e.(tty.KeyboardEvent).Key.Is(tty.SpecialKey_F12))
-			fmt.Println("\r")
+	var got []tty.Event
+	for len(got) < 4 {
+		select {
+		case e := <-ch:
+			got = append(got, e)
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
 		}
-	}()
+	}
 
-	tt.Wait()
+	if !got[0].(tty.KeyboardEvent).Key.Is(tty.SpecialKey_F12) {
+		t.Errorf("expected F12, got %v", got[0])
+	}
+	if me := got[1].(tty.MouseEvent); me.X != 3 || me.Y != 4 {
+		t.Errorf("unexpected mouse event %v", me)
+	}
+	if me := got[2].(tty.MouseEvent); me.X != 1 || me.Y != 2 || me.Action != tty.MouseAction_Release {
+		t.Errorf("unexpected parsed mouse event %v", me)
+	}
+	if re := got[3].(tty.ResizeEvent); re.Width != 100 || re.Height != 30 {
+		t.Errorf("unexpected resize event %v", re)
+	}
+	if w, h, _ := tt.WindowSize(); w != 100 || h != 30 {
+		t.Errorf("window size is %dx%d", w, h)
+	}
 }
 
 func TestSetCursor(t *testing.T) {
-	tt, err := tty.NewTTY()
-	if err != nil {
-		t.Fatal(err)
-		return
-	}
+	tt := tty.NewVirtualTTY(4, 3)
 	// clear screen
-	fmt.Print("\033[2J")
+	tt.Clear()
 
 	// move cursor on the screen
 	w, h, err := tt.WindowSize()
@@ -204,11 +216,81 @@ func TestSetCursor(t *testing.T) {
 		t.Fatal(err)
 		return
 	}
-	for i := 1; i <= w; i++ {
-		for j := 1; j <= h; j++ {
+	for i := 0; i < w; i++ {
+		for j := 0; j < h; j++ {
 			tt.WritePos(i, j, []byte{fmt.Sprintf("%d", i)[0]})
-			time.Sleep(1 * time.Millisecond)
 		}
 	}
 
+	if got := tt.Text(); got != "0123\n0123\n0123" {
+		t.Errorf("unexpected screen %q", got)
+	}
+	if x, y := tt.Cursor(); x != 3 || y != 2 {
+		t.Errorf("cursor at %d,%d", x, y)
+	}
+}
+
+func TestVirtualTTYScreen(t *testing.T) {
+	tt := tty.NewVirtualTTY(6, 2)
+	tt.Write([]byte("\033[?1000h\033[2;2H\033[38;5;3m\033[48;5;4mhé"))
+	// sequences split across writes are applied once complete
+	tt.Write([]byte("\033[m\033"))
+	tt.Write([]byte("[1;1Hx"))
+
+	if got := tt.Screen(); got[0] != "x     " || got[1] != " hé   " {
+		t.Errorf("unexpected screen %q", got)
+	}
+	if c := tt.CellAt(1, 1); c.Content != "h" || c.Foreground != dom.PaletteColor(3) || c.Background != dom.PaletteColor(4) {
+		t.Errorf("unexpected cell %+v", c)
+	}
+	if c := tt.CellAt(0, 0); !c.Foreground.IsDefault() || !c.Background.IsDefault() {
+		t.Errorf("style was not reset: %+v", c)
+	}
+	tt.Write([]byte("\033[1;6H\033[38;2;255;135;0;91mz"))
+	if c := tt.CellAt(5, 0); c.Foreground != dom.PaletteColor(9) {
+		t.Errorf("unexpected cell %+v", c)
+	}
+	tt.Write([]byte("\033[1;6H\033[0;48;2;255;135;0mz"))
+	if c := tt.CellAt(5, 0); c.Background != dom.RGBColor(255, 135, 0) {
+		t.Errorf("unexpected cell %+v", c)
+	}
+	tt.Write([]byte("\033[1;6H\033[0;1;3;4:3;58:2::1:2:3;7mz"))
+	want := view.Style{
+		Attrs:          view.Attr_Bold | view.Attr_Italic | view.Attr_Underline | view.Attr_Reverse,
+		UnderlineStyle: dom.TextDecorationStyle_Wavy,
+		UnderlineColor: dom.RGBColor(1, 2, 3),
+	}
+	if c := tt.CellAt(5, 0); c.Style != want {
+		t.Errorf("unexpected cell %+v", c)
+	}
+	tt.Write([]byte("\033[1;6H\033[22;23;24;59mz"))
+	if c := tt.CellAt(5, 0); c.Style != (view.Style{Attrs: view.Attr_Reverse}) {
+		t.Errorf("unexpected cell %+v", c)
+	}
+	if !tt.Mode("?1000") {
+		t.Error("mouse mode was not recorded")
+	}
+	if !strings.HasPrefix(string(tt.Output()), "\033[?1000h") {
+		t.Error("writes were not recorded")
+	}
+}
+
+func TestVirtualTTYWide(t *testing.T) {
+	tt := tty.NewVirtualTTY(8, 1)
+	tt.Write([]byte("a漢e\u0301👍🏽"))
+	if got := tt.Screen()[0]; got != "a漢e\u0301👍🏽  " {
+		t.Errorf("unexpected screen %q", got)
+	}
+	if c := tt.CellAt(2, 0); c.Content != "" {
+		t.Errorf("the second column of a wide character is not empty: %+v", c)
+	}
+	if x, _ := tt.Cursor(); x != 6 {
+		t.Errorf("cursor at %d", x)
+	}
+
+	// writing over half of a wide character blanks it
+	tt.Write([]byte("\033[1;3Hx"))
+	if got := tt.Screen()[0]; got != "a xe\u0301👍🏽  " {
+		t.Errorf("unexpected screen %q", got)
+	}
 }
@@ -0,0 +1,632 @@
+package tty
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+// --------------------
+//    Virtual TTY
+// --------------------
+
+// VirtualTTY is an in-memory terminal of a fixed size. It records every
+// write, keeps a screen model up to date by interpreting the escape
+// sequences the engine emits, and lets tests inject input events.
+type VirtualTTY struct {
+	mu      sync.Mutex
+	width   int
+	height  int
+	output  []byte
+	pending []byte
+	screen  [][]VirtualCell
+	cursor  [2]int
+	style   view.Style
+	modes   map[string]bool
+	closed  bool
+	caps    Capabilities
+	alt     bool
+	suspend int
+	writes  int
+	events  chan Event
+	parser  InputParser
+
+	// last is the cell written last, which combining characters join
+	// until the cursor moves.
+	last     [2]int
+	joinable bool
+}
+
+// VirtualCell is a single column of the virtual screen and the style
+// it was written with.
+type VirtualCell struct {
+	// Content is the grapheme cluster in the cell, empty in the second
+	// column of a wide one.
+	Content string
+	view.Style
+}
+
+func NewVirtualTTY(width, height int) *VirtualTTY {
+	t := &VirtualTTY{
+		width:  width,
+		height: height,
+		modes:  make(map[string]bool),
+		caps:   virtualCapabilities(),
+		events: make(chan Event, 64),
+		parser: newInputParser(),
+	}
+	t.screen = newVirtualScreen(width, height)
+	return t
+}
+
+// virtualCapabilities are those of an xterm with synchronized output.
+func virtualCapabilities() Capabilities {
+	c := DefaultCapabilities()
+	c.SynchronizedOutput = true
+	return c
+}
+
+func newVirtualScreen(width, height int) [][]VirtualCell {
+	screen := make([][]VirtualCell, height)
+	for y := range screen {
+		screen[y] = make([]VirtualCell, width)
+		for x := range screen[y] {
+			screen[y][x].Content = " "
+		}
+	}
+	return screen
+}
+
+// --------------------
+//    TTY Interface
+// --------------------
+
+func (t *VirtualTTY) WindowSize() (int, int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.width, t.height, nil
+}
+
+// Watch forwards injected events to ch until ctx is done.
+func (t *VirtualTTY) Watch(ctx context.Context, ch chan Event) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-t.events:
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (t *VirtualTTY) SetPos(x, y int) {
+	t.Write([]byte("\033[" + strconv.Itoa(y+1) + ";" + strconv.Itoa(x+1) + "H"))
+}
+
+func (t *VirtualTTY) WritePos(x, y int, b []byte) (int, error) {
+	t.SetPos(x, y)
+	return t.Write(b)
+}
+
+func (t *VirtualTTY) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writes++
+	t.output = append(t.output, b...)
+	t.pending = append(t.pending, b...)
+	t.interpret()
+	return len(b), nil
+}
+
+// Capabilities returns what the terminal claims to support: by default a
+// 256 color xterm with synchronized output.
+func (t *VirtualTTY) Capabilities() Capabilities {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.caps
+}
+
+func (t *VirtualTTY) SetCapabilities(c Capabilities) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.caps = c
+}
+
+func (t *VirtualTTY) SynchronizedOutput() bool {
+	return t.Capabilities().SynchronizedOutput
+}
+
+func (t *VirtualTTY) SetSynchronizedOutput(on bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.caps.SynchronizedOutput = on
+}
+
+func (t *VirtualTTY) Clear() {
+	t.Write([]byte("\033[2J"))
+}
+
+// Close restores the terminal, see Restore, and marks it closed.
+func (t *VirtualTTY) Close() error {
+	if t.Closed() {
+		return nil
+	}
+	t.Restore()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+// --------------------
+//   Terminal State
+// --------------------
+
+func (t *VirtualTTY) EnterAltScreen() {
+	t.mu.Lock()
+	t.alt = true
+	t.mu.Unlock()
+	t.Write([]byte(altScreenEnable))
+}
+
+func (t *VirtualTTY) ExitAltScreen() {
+	t.mu.Lock()
+	t.alt = false
+	t.mu.Unlock()
+	t.Write([]byte(altScreenDisable))
+}
+
+// Restore writes what a real terminal is restored with: mouse reporting
+// and bracketed paste off, default style, cursor shown and, when it was
+// entered, the main screen.
+func (t *VirtualTTY) Restore() error {
+	t.mu.Lock()
+	seq := mouseDisable + styleReset + cursorShow + pasteDisable
+	if t.alt {
+		seq += altScreenDisable
+	}
+	t.mu.Unlock()
+	t.Write([]byte(seq))
+	return nil
+}
+
+// Resume undoes Restore.
+func (t *VirtualTTY) Resume() error {
+	t.mu.Lock()
+	seq := cursorHide + pasteEnable
+	if t.alt {
+		seq = altScreenEnable + seq
+	}
+	t.mu.Unlock()
+	t.Write([]byte(seq))
+	return nil
+}
+
+// Suspend restores and resumes the terminal right away and counts the
+// call, without stopping the process.
+func (t *VirtualTTY) Suspend() error {
+	t.Restore()
+	t.mu.Lock()
+	t.suspend++
+	t.mu.Unlock()
+	return t.Resume()
+}
+
+// Suspends returns how many times Suspend was called.
+func (t *VirtualTTY) Suspends() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.suspend
+}
+
+// --------------------
+//     Injection
+// --------------------
+
+// Send queues ev as if it had been read from the terminal.
+func (t *VirtualTTY) Send(ev Event) {
+	t.events <- ev
+}
+
+func (t *VirtualTTY) SendKey(ev KeyboardEvent) {
+	t.Send(ev)
+}
+
+func (t *VirtualTTY) SendMouse(ev MouseEvent) {
+	t.Send(ev)
+}
+
+// SendPaste sends text the way a terminal in bracketed paste mode does.
+func (t *VirtualTTY) SendPaste(text string) {
+	t.SendBytes([]byte(string(pasteStart) + text + string(pasteEnd)))
+}
+
+// SendBytes runs b through the input parser of a real terminal and queues
+// the resulting events. A sequence left unfinished at the end of b is
+// flushed as if the escape timeout had passed.
+func (t *VirtualTTY) SendBytes(b []byte) {
+	for _, ev := range append(t.parser.Parse(b), t.parser.Flush()...) {
+		if ev.Type() != eventType_Reply {
+			t.Send(ev)
+		}
+	}
+}
+
+// Resize changes the size of the terminal and queues the matching resize
+// event.
+func (t *VirtualTTY) Resize(width, height int) {
+	t.SetSize(width, height)
+	t.Send(ResizeEvent{Width: width, Height: height})
+}
+
+// SetSize changes the size of the terminal, clearing the screen, without
+// queuing an event.
+func (t *VirtualTTY) SetSize(width, height int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.width, t.height = width, height
+	t.screen = newVirtualScreen(width, height)
+	t.cursor = [2]int{}
+	t.joinable = false
+}
+
+// --------------------
+//     Inspection
+// --------------------
+
+// Output returns every byte written so far.
+func (t *VirtualTTY) Output() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]byte(nil), t.output...)
+}
+
+// Writes returns the number of Write calls so far, including those made by
+// SetPos, WritePos and Clear.
+func (t *VirtualTTY) Writes() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.writes
+}
+
+// ResetOutput forgets the recorded writes. The screen is kept.
+func (t *VirtualTTY) ResetOutput() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.output = nil
+	t.writes = 0
+}
+
+func (t *VirtualTTY) Closed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// Mode reports whether the private mode, e.g. "?1000", was last set with
+// an "h" sequence.
+func (t *VirtualTTY) Mode(mode string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.modes[mode]
+}
+
+// Cursor returns the 0-based position of the cursor.
+func (t *VirtualTTY) Cursor() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cursor[0], t.cursor[1]
+}
+
+func (t *VirtualTTY) CellAt(x, y int) VirtualCell {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if x < 0 || y < 0 || y >= len(t.screen) || x >= len(t.screen[y]) {
+		return VirtualCell{}
+	}
+	return t.screen[y][x]
+}
+
+// Screen returns the text of every row of the screen.
+func (t *VirtualTTY) Screen() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rows := make([]string, len(t.screen))
+	for y, row := range t.screen {
+		var sb strings.Builder
+		for _, c := range row {
+			sb.WriteString(c.Content)
+		}
+		rows[y] = sb.String()
+	}
+	return rows
+}
+
+// Text returns the screen as newline separated rows with trailing spaces
+// removed.
+func (t *VirtualTTY) Text() string {
+	rows := t.Screen()
+	for i := range rows {
+		rows[i] = strings.TrimRight(rows[i], " ")
+	}
+	return strings.Join(rows, "\n")
+}
+
+// --------------------
+//   Output Parsing
+// --------------------
+
+// interpret applies the complete sequences and characters of the pending
+// output to the screen. An unfinished escape sequence or rune is kept for
+// the next write.
+func (t *VirtualTTY) interpret() {
+	b := t.pending
+	for len(b) > 0 {
+		if b[0] == '\033' {
+			n := t.escape(b)
+			if n == 0 {
+				break
+			}
+			b = b[n:]
+			continue
+		}
+		if !utf8.FullRune(b) {
+			break
+		}
+		r, n := utf8.DecodeRune(b)
+		b = b[n:]
+		t.put(r)
+	}
+	t.pending = append(t.pending[:0], b...)
+}
+
+// escape applies the escape sequence at the start of b and returns its
+// length, or 0 when it is not complete yet.
+func (t *VirtualTTY) escape(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	if b[1] != '[' {
+		return 2
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			t.csi(string(b[2:i]), b[i])
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (t *VirtualTTY) csi(params string, final byte) {
+	if strings.HasPrefix(params, "?") {
+		for _, p := range strings.Split(params[1:], ";") {
+			switch final {
+			case 'h':
+				t.modes["?"+p] = true
+			case 'l':
+				t.modes["?"+p] = false
+			}
+		}
+		return
+	}
+
+	args := csiArgs(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	switch final {
+	case 'H', 'f':
+		t.moveTo(arg(1, 1)-1, arg(0, 1)-1)
+	case 'A':
+		t.moveTo(t.cursor[0], t.cursor[1]-arg(0, 1))
+	case 'B':
+		t.moveTo(t.cursor[0], t.cursor[1]+arg(0, 1))
+	case 'C':
+		t.moveTo(t.cursor[0]+arg(0, 1), t.cursor[1])
+	case 'D':
+		t.moveTo(t.cursor[0]-arg(0, 1), t.cursor[1])
+	case 'G':
+		t.moveTo(arg(0, 1)-1, t.cursor[1])
+	case 'J':
+		if arg(0, 0) == 2 {
+			t.screen = newVirtualScreen(t.width, t.height)
+			t.joinable = false
+		}
+	case 'K':
+		if y := t.cursor[1]; y < len(t.screen) {
+			for x := t.cursor[0]; x < len(t.screen[y]); x++ {
+				t.screen[y][x] = VirtualCell{Content: " "}
+			}
+		}
+	case 'm':
+		t.sgr(params)
+	}
+}
+
+// sgr applies the SGR parameters to the style of the following writes.
+// Subparameters may be separated by colons, as in "4:3" or "58:2::1:2:3".
+func (t *VirtualTTY) sgr(params string) {
+	parts := strings.Split(params, ";")
+	for i := 0; i < len(parts); i++ {
+		sub := strings.Split(parts[i], ":")
+		switch a, _ := strconv.Atoi(sub[0]); {
+		case a == 0:
+			t.style = view.Style{}
+		case a == 38 || a == 48 || a == 58:
+			c, n := sgrColor(sub[1:])
+			if len(sub) == 1 {
+				c, n = sgrColor(parts[i+1:])
+				i += n
+			}
+			if n == 0 {
+				continue
+			}
+			switch a {
+			case 38:
+				t.style.Foreground = c
+			case 48:
+				t.style.Background = c
+			default:
+				t.style.UnderlineColor = c
+			}
+		case a == 39:
+			t.style.Foreground = dom.ColorDefault
+		case a == 49:
+			t.style.Background = dom.ColorDefault
+		case a == 59:
+			t.style.UnderlineColor = dom.ColorDefault
+		case a >= 30 && a <= 37:
+			t.style.Foreground = dom.PaletteColor(uint8(a - 30))
+		case a >= 40 && a <= 47:
+			t.style.Background = dom.PaletteColor(uint8(a - 40))
+		case a >= 90 && a <= 97:
+			t.style.Foreground = dom.PaletteColor(uint8(a - 90 + 8))
+		case a >= 100 && a <= 107:
+			t.style.Background = dom.PaletteColor(uint8(a - 100 + 8))
+		case a == 4:
+			t.style.Attrs |= view.Attr_Underline
+			t.style.UnderlineStyle = dom.TextDecorationStyle_Solid
+			if len(sub) > 1 {
+				switch n, _ := strconv.Atoi(sub[1]); n {
+				case 0:
+					t.style.Attrs &^= view.Attr_Underline
+				case 2, 3, 4, 5:
+					t.style.UnderlineStyle = dom.TextDecorationStyle(n - 1)
+				}
+			}
+		case a == 22:
+			t.style.Attrs &^= view.Attr_Bold | view.Attr_Dim
+		case a == 24:
+			t.style.Attrs &^= view.Attr_Underline
+			t.style.UnderlineStyle = dom.TextDecorationStyle_Solid
+		default:
+			if attr, ok := sgrAttrs[a]; ok {
+				t.style.Attrs |= attr
+			} else if attr, ok := sgrAttrs[a-20]; ok && a > 20 {
+				t.style.Attrs &^= attr
+			}
+		}
+	}
+}
+
+// sgrAttrs maps the SGR parameters that turn attributes on to them. 20 more
+// turns them off.
+var sgrAttrs = map[int]view.Attr{
+	1: view.Attr_Bold,
+	2: view.Attr_Dim,
+	3: view.Attr_Italic,
+	4: view.Attr_Underline,
+	5: view.Attr_Blink,
+	7: view.Attr_Reverse,
+	9: view.Attr_Strikethrough,
+}
+
+// sgrColor parses the color following 38, 48 or 58, either "5;n" or
+// "2;r;g;b", and returns how many parameters it took.
+func sgrColor(args []string) (dom.Color, int) {
+	num := func(s string) uint8 {
+		n, _ := strconv.Atoi(s)
+		return uint8(n)
+	}
+	switch {
+	case len(args) >= 2 && args[0] == "5":
+		return dom.PaletteColor(num(args[1])), 2
+	case len(args) >= 5 && args[0] == "2" && args[1] == "":
+		// the colon form may leave the color space out
+		return dom.RGBColor(num(args[2]), num(args[3]), num(args[4])), 5
+	case len(args) >= 4 && args[0] == "2":
+		return dom.RGBColor(num(args[1]), num(args[2]), num(args[3])), 4
+	}
+	return dom.ColorDefault, 0
+}
+
+func csiArgs(params string) []int {
+	if params == "" {
+		return nil
+	}
+	parts := strings.Split(params, ";")
+	args := make([]int, len(parts))
+	for i, p := range parts {
+		args[i], _ = strconv.Atoi(p)
+	}
+	return args
+}
+
+func (t *VirtualTTY) moveTo(x, y int) {
+	t.joinable = false
+	t.cursor[0] = clampInt(x, 0, t.width-1)
+	t.cursor[1] = clampInt(y, 0, t.height-1)
+}
+
+func clampInt(n, lo, hi int) int {
+	if n > hi {
+		n = hi
+	}
+	if n < lo {
+		n = lo
+	}
+	return n
+}
+
+// put writes r at the cursor the way a terminal does: a wide character
+// takes two columns and a combining one joins the character before it.
+func (t *VirtualTTY) put(r rune) {
+	switch r {
+	case '\r':
+		t.moveTo(0, t.cursor[1])
+		return
+	case '\n':
+		t.moveTo(t.cursor[0], t.cursor[1]+1)
+		return
+	}
+	if t.joinable {
+		c := &t.screen[t.last[1]][t.last[0]]
+		joined := c.Content + string(r)
+		if g, _ := view.FirstGrapheme(joined); len(g) == len(joined) {
+			c.Content = joined
+			return
+		}
+	}
+	x, y := t.cursor[0], t.cursor[1]
+	w := view.RuneWidth(r)
+	if w == 0 || y >= len(t.screen) || x >= len(t.screen[y]) {
+		return
+	}
+	row := t.screen[y]
+	if x+w > len(row) {
+		r, w = ' ', 1
+	}
+	splitVirtualCells(row, x)
+	splitVirtualCells(row, x+w)
+	row[x] = VirtualCell{Content: string(r), Style: t.style}
+	if w == 2 {
+		row[x+1] = VirtualCell{Style: t.style}
+	}
+	t.last, t.joinable = [2]int{x, y}, true
+	if x+w < t.width {
+		t.cursor[0] = x + w
+	}
+}
+
+// splitVirtualCells blanks a wide character that spans the cells x-1 and
+// x, as terminals do when one of its halves is written over.
+func splitVirtualCells(row []VirtualCell, x int) {
+	if x <= 0 || x >= len(row) || row[x].Content != "" {
+		return
+	}
+	row[x-1].Content, row[x].Content = " ", " "
+}
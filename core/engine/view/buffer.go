@@ -2,6 +2,7 @@ package view
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/saman3d/samtui/core/dom"
 )
@@ -12,53 +13,199 @@ type Position struct {
 }
 
 type Cell struct {
-	Style   Style
-	Content rune
+	Style Style
+	// Content is the grapheme cluster shown in the cell. A wide one takes
+	// the next cell too, which is then a continuation with no content.
+	Content string
 	Element *dom.Element
 	ZIndex  uint8
 }
 
 func newCell() *Cell {
 	return &Cell{
-		Content: ' ',
+		Content: " ",
 	}
 }
 
 func (c *Cell) Flush() {
 	c.Style = Style{}
-	c.Content = ' '
+	c.Content = " "
 	c.Element = nil
 }
 
+// Continuation tells whether c is the second column of a wide cluster.
+func (c *Cell) Continuation() bool {
+	return c.Content == ""
+}
+
 func (c *Cell) String() string {
-	return fmt.Sprintf("%s%c", c.Style, c.Content)
+	return fmt.Sprintf("%s%s", c.Style, c.Content)
 }
 
 func (c *Cell) Bytes() []byte {
 	return []byte(c.String())
 }
 
+// Attr is a set of text attributes.
+type Attr uint8
+
+const (
+	Attr_Bold Attr = 1 << iota
+	Attr_Dim
+	Attr_Italic
+	Attr_Underline
+	Attr_Blink
+	Attr_Reverse
+	Attr_Strikethrough
+)
+
+// attrCodes holds the SGR parameters that turn each attribute on and off,
+// in the order of the attributes. Bold and dim are both turned off by 22.
+var attrCodes = [...][2]string{
+	{";1", ";22"},
+	{";2", ";22"},
+	{";3", ";23"},
+	{";4", ";24"},
+	{";5", ";25"},
+	{";7", ";27"},
+	{";9", ";29"},
+}
+
+// underlineCodes holds the SGR parameters of the underline styles.
+var underlineCodes = [...]string{
+	dom.TextDecorationStyle_Solid:  ";4",
+	dom.TextDecorationStyle_Double: ";4:2",
+	dom.TextDecorationStyle_Wavy:   ";4:3",
+	dom.TextDecorationStyle_Dotted: ";4:4",
+	dom.TextDecorationStyle_Dashed: ";4:5",
+}
+
 type Style struct {
-	Foreground int
-	Background int
+	Foreground dom.Color
+	Background dom.Color
+	Attrs      Attr
+	// UnderlineStyle and UnderlineColor are how the underline is drawn
+	// when Attrs has Attr_Underline.
+	UnderlineStyle dom.TextDecorationStyle
+	UnderlineColor dom.Color
 }
 
-func NewStyle(fg, bg int) Style {
+func NewStyle(fg, bg dom.Color) Style {
 	return Style{
 		Foreground: fg,
 		Background: bg,
 	}
 }
 
+// Profile is what a terminal can draw of a Style.
+type Profile struct {
+	Colors ColorDepth
+	Italic bool
+	// Undercurl is whether the terminal draws the underline styles other
+	// than solid and colors the underline.
+	Undercurl bool
+}
+
+// FullProfile draws every Style as it is.
+var FullProfile = Profile{Colors: ColorDepth_TrueColor, Italic: true, Undercurl: true}
+
+// String returns the SGR sequence that resets the terminal style and sets s
+// on a terminal that draws everything.
 func (s Style) String() string {
-	if s.Background == 0 && s.Foreground == 0 {
-		return fmt.Sprintf("\033[m")
-	} else if s.Background == 0 {
-		return fmt.Sprintf("\033[m\033[38;5;%vm", s.Foreground)
-	} else if s.Foreground == 0 {
-		return fmt.Sprintf("\033[m\033[48;5;%vm", s.Background)
+	return s.StringFor(FullProfile)
+}
+
+// StringFor is String for a terminal of the given profile.
+func (s Style) StringFor(p Profile) string {
+	s = s.within(p)
+	return "\033[0" + s.attrParams(s.Attrs) + s.colorParams(Style{}, p.Colors) + "m"
+}
+
+// Transition returns the shortest SGR sequence that changes the terminal
+// style from the given one to s on a terminal that draws everything, or ""
+// when they are the same.
+func (s Style) Transition(from Style) string {
+	return s.TransitionFor(from, FullProfile)
+}
+
+// TransitionFor is Transition for a terminal of the given profile.
+func (s Style) TransitionFor(from Style, p Profile) string {
+	s, from = s.within(p), from.within(p)
+	if s == from {
+		return ""
+	}
+	var params string
+	on, off := s.Attrs&^from.Attrs, from.Attrs&^s.Attrs
+	if off&(Attr_Bold|Attr_Dim) != 0 {
+		params += ";22"
+		on |= s.Attrs & (Attr_Bold | Attr_Dim)
+		off &^= Attr_Bold | Attr_Dim
+	}
+	for i, codes := range attrCodes {
+		if off&(1<<i) != 0 {
+			params += codes[1]
+		}
+	}
+	if s.Attrs&from.Attrs&Attr_Underline != 0 && s.UnderlineStyle != from.UnderlineStyle {
+		on |= Attr_Underline
+	}
+	params += s.attrParams(on) + s.colorParams(from, p.Colors)
+	return "\033[" + params[1:] + "m"
+}
+
+// within returns s without what the terminal of the given profile cannot
+// draw.
+func (s Style) within(p Profile) Style {
+	if s.Attrs&Attr_Underline == 0 || !p.Undercurl {
+		s.UnderlineStyle = dom.TextDecorationStyle_Solid
+		s.UnderlineColor = dom.ColorDefault
+	}
+	if !p.Italic {
+		s.Attrs &^= Attr_Italic
+	}
+	if p.Colors == ColorDepth_None {
+		s.Foreground, s.Background, s.UnderlineColor = dom.ColorDefault, dom.ColorDefault, dom.ColorDefault
+	}
+	return s
+}
+
+// attrParams returns the SGR parameters that turn the given attributes on.
+func (s Style) attrParams(attrs Attr) string {
+	var params string
+	for i, codes := range attrCodes {
+		if attrs&(1<<i) == 0 {
+			continue
+		}
+		if Attr(1<<i) == Attr_Underline && int(s.UnderlineStyle) < len(underlineCodes) {
+			params += underlineCodes[s.UnderlineStyle]
+			continue
+		}
+		params += codes[0]
+	}
+	return params
+}
+
+// colorParams returns the SGR parameters that change the colors from those
+// of the given style to those of s.
+func (s Style) colorParams(from Style, depth ColorDepth) string {
+	var params string
+	for _, c := range []struct {
+		to, from dom.Color
+		base     int
+	}{
+		{s.Foreground, from.Foreground, 30},
+		{s.Background, from.Background, 40},
+		{s.UnderlineColor, from.UnderlineColor, 50},
+	} {
+		switch {
+		case c.to == c.from:
+		case c.to.IsDefault():
+			params += ";" + strconv.Itoa(c.base+9)
+		default:
+			params += colorParams(c.to, c.base, depth)
+		}
 	}
-	return fmt.Sprintf("\033[38;5;%vm\033[48;5;%vm", s.Foreground, s.Background)
+	return params
 }
 
 type AxisMask byte
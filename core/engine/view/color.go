@@ -0,0 +1,132 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/saman3d/samtui/core/dom"
+)
+
+// ColorDepth is the number of colors a terminal can show.
+type ColorDepth int
+
+const (
+	ColorDepth_None      ColorDepth = 0
+	ColorDepth_8         ColorDepth = 8
+	ColorDepth_16        ColorDepth = 16
+	ColorDepth_256       ColorDepth = 256
+	ColorDepth_TrueColor ColorDepth = 1 << 24
+)
+
+// colorParams returns the SGR parameters that set c as the foreground, or
+// the background when base is 40 and the underline when base is 50, at the
+// given depth. Colors beyond the depth are replaced by the closest one
+// within it.
+func colorParams(c dom.Color, base int, depth ColorDepth) string {
+	if depth == ColorDepth_None {
+		return ""
+	}
+	n, _ := c.Palette()
+	if r, g, b, ok := c.RGB(); ok {
+		rgb := [3]int{int(r), int(g), int(b)}
+		switch {
+		case depth >= ColorDepth_TrueColor:
+			return fmt.Sprintf(";%d;2;%d;%d;%d", base+8, r, g, b)
+		case depth >= ColorDepth_256:
+			n = nearestPalette(rgb)
+		default:
+			n = nearestANSI(rgb)
+		}
+	}
+	if n >= 16 && depth < ColorDepth_256 {
+		n = nearestANSI(paletteRGB(n))
+	}
+	// the underline color has no short form
+	if depth >= ColorDepth_256 || base == 50 {
+		return ";" + strconv.Itoa(base+8) + ";5;" + strconv.Itoa(int(n))
+	}
+	if n >= 8 && depth < ColorDepth_16 {
+		n -= 8
+	}
+	if n >= 8 {
+		// the bright colors
+		return ";" + strconv.Itoa(base+60+int(n)-8)
+	}
+	return ";" + strconv.Itoa(base+int(n))
+}
+
+// ansiPalette holds the xterm defaults of the first 16 palette colors.
+var ansiPalette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// paletteRGB returns the xterm default of the palette color n.
+func paletteRGB(n uint8) [3]int {
+	switch {
+	case n < 16:
+		return ansiPalette[n]
+	case n < 232:
+		// the 6x6x6 color cube
+		n -= 16
+		return [3]int{cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]}
+	default:
+		// the gray ramp
+		g := 8 + int(n-232)*10
+		return [3]int{g, g, g}
+	}
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// nearestPalette returns the color of the cube or the gray ramp of the 256
+// color palette closest to rgb. The first 16 colors are left out as the
+// terminal theme changes them.
+func nearestPalette(rgb [3]int) uint8 {
+	var cube [3]int
+	for i, v := range rgb {
+		for l := range cubeLevels {
+			if abs(v-cubeLevels[l]) < abs(v-cubeLevels[cube[i]]) {
+				cube[i] = l
+			}
+		}
+	}
+	best := uint8(16 + cube[0]*36 + cube[1]*6 + cube[2])
+
+	avg := (rgb[0] + rgb[1] + rgb[2]) / 3
+	gray := uint8(232)
+	if step := (avg - 3) / 10; step > 23 {
+		gray = 255
+	} else if step > 0 {
+		gray += uint8(step)
+	}
+	if distance(rgb, paletteRGB(gray)) < distance(rgb, paletteRGB(best)) {
+		return gray
+	}
+	return best
+}
+
+// nearestANSI returns the first 16 palette color closest to rgb.
+func nearestANSI(rgb [3]int) uint8 {
+	var best uint8
+	for i, c := range ansiPalette {
+		if distance(rgb, c) < distance(rgb, ansiPalette[best]) {
+			best = uint8(i)
+		}
+	}
+	return best
+}
+
+func distance(a, b [3]int) int {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package view
+
+// --------------------
+//    Front Buffer
+// --------------------
+
+// Grid is a buffer of cells that can be diffed against the front buffer.
+type Grid interface {
+	Width() int64
+	Height() int64
+	GetCell(x, y int) *Cell
+}
+
+// Front is the front buffer: a copy of what the terminal currently shows.
+// The View is drawn into as the back buffer and only the cells that differ
+// from the front are written out.
+type Front struct {
+	cells [][]Cell
+}
+
+// Run is a horizontal stretch of changed cells starting at X, Y. The
+// continuations of wide clusters are part of it, so that the cursor moves
+// past them, but have nothing to write.
+type Run struct {
+	X     int
+	Y     int
+	Cells []Cell
+}
+
+// NewFront returns a front buffer for a freshly cleared screen.
+func NewFront(width, height int) *Front {
+	f := &Front{}
+	f.Resize(width, height)
+	f.Clear()
+	return f
+}
+
+// Resize changes the size of the buffer. Its content is invalidated, since
+// terminals reflow or clear the screen when resized.
+func (f *Front) Resize(width, height int) {
+	f.cells = make([][]Cell, height)
+	for y := range f.cells {
+		f.cells[y] = make([]Cell, width)
+	}
+	f.Invalidate()
+}
+
+// Invalidate forgets what the terminal shows so that the next Diff reports
+// every cell.
+func (f *Front) Invalidate() {
+	for _, row := range f.cells {
+		for x := range row {
+			// no drawn cell holds a control character
+			row[x] = Cell{Content: "\x00"}
+		}
+	}
+}
+
+// Clear records that the terminal was cleared.
+func (f *Front) Clear() {
+	for _, row := range f.cells {
+		for x := range row {
+			row[x] = Cell{Content: " "}
+		}
+	}
+}
+
+// Diff returns the runs of cells of back that differ from the front and
+// records them as shown. When back is of another size the front is resized
+// first, so that every cell is reported.
+func (f *Front) Diff(back Grid) []Run {
+	var runs []Run
+	height, width := int(back.Height()), int(back.Width())
+	if height != len(f.cells) || height > 0 && width != len(f.cells[0]) {
+		f.Resize(width, height)
+	}
+	for y := 0; y < height && y < len(f.cells); y++ {
+		row := f.cells[y]
+		var run *Run
+		for x := 0; x < width && x < len(row); x++ {
+			c := back.GetCell(x, y)
+			if row[x].Content == c.Content && row[x].Style == c.Style {
+				run = nil
+				continue
+			}
+			row[x] = Cell{Content: c.Content, Style: c.Style}
+			if run == nil {
+				runs = append(runs, Run{X: x, Y: y})
+				run = &runs[len(runs)-1]
+				if row[x].Continuation() && x > 0 {
+					// a run starts with the cluster, not its continuation
+					run.X--
+					run.Cells = append(run.Cells, row[x-1])
+				}
+			}
+			run.Cells = append(run.Cells, row[x])
+		}
+	}
+	return runs
+}
@@ -0,0 +1,129 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"gotest.tools/v3/assert"
+)
+
+func TestFrontDiff(t *testing.T) {
+	v := NewView(6, 2)
+	f := NewFront(6, 2)
+
+	assert.Equal(t, len(f.Diff(v)), 0, "a blank view matches a cleared screen")
+
+	v.PrintString(1, 0, NewStyle(dom.PaletteColor(1), dom.ColorDefault), 0, nil, "ab")
+	v.PrintString(4, 0, NewStyle(dom.PaletteColor(1), dom.ColorDefault), 0, nil, "c")
+	runs := f.Diff(v)
+	assert.Equal(t, len(runs), 2)
+	assert.Equal(t, runs[0].X, 1)
+	assert.Equal(t, runs[0].Cells[0].Content+runs[0].Cells[1].Content, "ab")
+	assert.Equal(t, runs[1].X, 4)
+	assert.Equal(t, len(runs[1].Cells), 1)
+
+	assert.Equal(t, len(f.Diff(v)), 0, "nothing changed since the last diff")
+
+	v.PrintString(2, 0, NewStyle(dom.PaletteColor(2), dom.ColorDefault), 0, nil, "b")
+	runs = f.Diff(v)
+	assert.Equal(t, len(runs), 1, "a style change alone is a change")
+	assert.Equal(t, runs[0].X, 2)
+
+	f.Invalidate()
+	runs = f.Diff(v)
+	assert.Equal(t, len(runs), 2, "one run per row after invalidation")
+	assert.Equal(t, len(runs[0].Cells), 6)
+}
+
+func TestStyleTransition(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		from, to Style
+		want     string
+	}{
+		{"same", NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)), NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)), ""},
+		{"foreground", NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)), NewStyle(dom.PaletteColor(3), dom.PaletteColor(2)), "\033[38;5;3m"},
+		{"background", NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)), NewStyle(dom.PaletteColor(1), dom.PaletteColor(4)), "\033[48;5;4m"},
+		{"both", NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)), NewStyle(dom.PaletteColor(3), dom.PaletteColor(4)), "\033[38;5;3;48;5;4m"},
+		{"to defaults", NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)), Style{}, "\033[39;49m"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.to.Transition(tc.from), tc.want)
+		})
+	}
+	assert.Equal(t, Style{}.String(), "\033[0m")
+	assert.Equal(t, NewStyle(dom.PaletteColor(1), dom.PaletteColor(2)).String(), "\033[0;38;5;1;48;5;2m")
+}
+
+func TestStyleColorDepth(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		style Style
+		depth ColorDepth
+		want  string
+	}{
+		{"256 colors", NewStyle(dom.PaletteColor(196), dom.PaletteColor(4)), ColorDepth_256, "\033[0;38;5;196;48;5;4m"},
+		{"true color", NewStyle(dom.PaletteColor(196), dom.PaletteColor(4)), ColorDepth_TrueColor, "\033[0;38;5;196;48;5;4m"},
+		{"16 colors", NewStyle(dom.PaletteColor(3), dom.PaletteColor(12)), ColorDepth_16, "\033[0;33;104m"},
+		{"cube to 16", NewStyle(dom.PaletteColor(196), dom.PaletteColor(21)), ColorDepth_16, "\033[0;91;44m"},
+		{"gray to 16", NewStyle(dom.PaletteColor(255), dom.PaletteColor(232)), ColorDepth_16, "\033[0;37;40m"},
+		{"8 colors", NewStyle(dom.PaletteColor(9), dom.PaletteColor(196)), ColorDepth_8, "\033[0;31;41m"},
+		{"no colors", NewStyle(dom.PaletteColor(9), dom.PaletteColor(196)), ColorDepth_None, "\033[0m"},
+		{"rgb", NewStyle(dom.RGBColor(30, 144, 255), dom.RGBColor(18, 18, 18)), ColorDepth_TrueColor, "\033[0;38;2;30;144;255;48;2;18;18;18m"},
+		{"rgb to 256", NewStyle(dom.RGBColor(30, 144, 255), dom.RGBColor(18, 18, 18)), ColorDepth_256, "\033[0;38;5;33;48;5;233m"},
+		{"rgb to 16", NewStyle(dom.RGBColor(30, 144, 255), dom.RGBColor(18, 18, 18)), ColorDepth_16, "\033[0;94;40m"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.style.StringFor(Profile{Colors: tc.depth}), tc.want)
+		})
+	}
+	assert.Equal(t, NewStyle(dom.PaletteColor(9), dom.ColorDefault).TransitionFor(Style{}, Profile{Colors: ColorDepth_16}), "\033[91m")
+	assert.Equal(t, NewStyle(dom.PaletteColor(9), dom.ColorDefault).TransitionFor(Style{}, Profile{}), "")
+}
+
+func TestStyleAttrs(t *testing.T) {
+	bold := Style{Attrs: Attr_Bold}
+	curly := Style{
+		Foreground:     dom.PaletteColor(1),
+		Attrs:          Attr_Italic | Attr_Underline,
+		UnderlineStyle: dom.TextDecorationStyle_Wavy,
+		UnderlineColor: dom.RGBColor(255, 0, 0),
+	}
+	for _, tc := range []struct {
+		name    string
+		style   Style
+		profile Profile
+		want    string
+	}{
+		{"every attribute", Style{Attrs: Attr_Bold | Attr_Dim | Attr_Italic | Attr_Underline | Attr_Blink | Attr_Reverse | Attr_Strikethrough}, FullProfile, "\033[0;1;2;3;4;5;7;9m"},
+		{"styled underline", curly, FullProfile, "\033[0;3;4:3;38;5;1;58;2;255;0;0m"},
+		{"underline color at 16 colors", curly, Profile{Colors: ColorDepth_16, Italic: true, Undercurl: true}, "\033[0;3;4:3;31;58;5;9m"},
+		{"no undercurl", curly, Profile{Colors: ColorDepth_256, Italic: true}, "\033[0;3;4;38;5;1m"},
+		{"no italic", curly, Profile{Colors: ColorDepth_256}, "\033[0;4;38;5;1m"},
+		{"attributes without colors", Style{Foreground: dom.PaletteColor(1), Attrs: Attr_Reverse}, Profile{}, "\033[0;7m"},
+		{"underline style without underline", Style{UnderlineStyle: dom.TextDecorationStyle_Double}, FullProfile, "\033[0m"},
+		{"bold", bold, FullProfile, "\033[0;1m"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.style.StringFor(tc.profile), tc.want)
+		})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		from, to Style
+		want     string
+	}{
+		{"turn on", Style{}, bold, "\033[1m"},
+		{"turn off", Style{Attrs: Attr_Italic | Attr_Strikethrough}, Style{}, "\033[23;29m"},
+		{"bold to dim", bold, Style{Attrs: Attr_Dim}, "\033[22;2m"},
+		{"dim off keeps bold", Style{Attrs: Attr_Bold | Attr_Dim}, bold, "\033[22;1m"},
+		{"underline style", Style{Attrs: Attr_Underline}, Style{Attrs: Attr_Underline, UnderlineStyle: dom.TextDecorationStyle_Double}, "\033[4:2m"},
+		{"underline off", curly, Style{Foreground: dom.PaletteColor(1), Attrs: Attr_Italic}, "\033[24;59m"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.to.Transition(tc.from), tc.want)
+		})
+	}
+	assert.Equal(t, Style{Attrs: Attr_Italic}.TransitionFor(Style{}, Profile{}), "", "the terminal cannot draw italic")
+}
@@ -59,63 +59,93 @@ func (v *View) Flush() {
 	}
 }
 
-func (v *View) PrintString(x, y, fg, bg int, zindx uint8, s string) {
-	for i, r := range s {
-		if (*v)[y][x+i].ZIndex <= zindx {
-			(*v)[y][x+i].Style = NewStyle(fg, bg)
-			(*v)[y][x+i].Content = r
-			(*v)[y][x+i].ZIndex = zindx
+// PrintString prints the grapheme clusters of s from x, y on, up to the
+// right edge of the view.
+func (v *View) PrintString(x, y int, style Style, zindx uint8, el *dom.Element, s string) {
+	v.PrintText(x, y, int(v.Width())-x, style, zindx, el, s)
+}
+
+// PrintText prints the grapheme clusters of s from x, y on within width
+// columns and returns the number of columns taken. A wide cluster that
+// does not fit in the last column is replaced by a space, and those that
+// take no column on their own, such as stray combining marks, are left
+// out.
+func (v *View) PrintText(x, y, width int, style Style, zindx uint8, el *dom.Element, s string) int {
+	if y < 0 || y >= len(*v) || x < 0 {
+		return 0
+	}
+	if rest := len((*v)[y]) - x; width > rest {
+		width = rest
+	}
+	col := 0
+	for s != "" && col < width {
+		g, w := FirstGrapheme(s)
+		s = s[len(g):]
+		if w == 0 {
+			continue
 		}
+		if col+w > width {
+			g, w = " ", 1
+		}
+		v.put(x+col, y, style, zindx, el, g, w)
+		col += w
 	}
+	return col
 }
 
-func (v *View) PrintRune(x, y, fg, bg int, zindx uint8, r rune) {
-	if (*v)[y][x].ZIndex <= zindx {
-		(*v)[y][x].ZIndex = zindx
-		(*v)[y][x].Style = NewStyle(fg, bg)
-		(*v)[y][x].Content = r
-	}
+// PrintRune prints r at x, y. A wide rune takes x+1 too.
+func (v *View) PrintRune(x, y int, style Style, zindx uint8, el *dom.Element, r rune) {
+	v.PrintText(x, y, RuneWidth(r), style, zindx, el, string(r))
 }
 
-func (v *View) PrintRuneRepeat(x, y, fg, bg, rp int, zindx uint8, axis AxisMask, r rune) {
-	switch axis {
-	case AxisMask_X:
-		for i := 0; i < rp; i++ {
-			if (*v)[y][x+i].ZIndex <= zindx {
-				(*v)[y][x+i].Style = NewStyle(fg, bg)
-				(*v)[y][x+i].Content = r
-				(*v)[y][x+i].ZIndex = zindx
-			}
-		}
-	case AxisMask_Y:
-		for i := 0; i < rp; i++ {
-			if (*v)[y+i][x].ZIndex <= zindx {
-				(*v)[y+i][x].Style = NewStyle(fg, bg)
-				(*v)[y+i][x].Content = r
-				(*v)[y+i][x].ZIndex = zindx
-			}
-		}
-	case AxisMask_X | AxisMask_Y:
-		for i := 0; i < rp; i++ {
+// PrintRuneRepeat prints r rp times from x, y on along the axis, or in a
+// square of rp by rp cells for both axes.
+func (v *View) PrintRuneRepeat(x, y int, style Style, rp int, zindx uint8, el *dom.Element, axis AxisMask, r rune) {
+	w := RuneWidth(r)
+	for i := 0; i < rp; i++ {
+		switch axis {
+		case AxisMask_Y:
+			v.PrintRune(x, y+i, style, zindx, el, r)
+		case AxisMask_X | AxisMask_Y:
 			for j := 0; j < rp; j++ {
-				if (*v)[y+i][x+j].ZIndex <= zindx {
-					(*v)[y+i][x+j].Style = NewStyle(fg, bg)
-					(*v)[y+i][x+j].Content = r
-					(*v)[y+i][x+j].ZIndex = zindx
-				}
-			}
-		}
-	default:
-		for i := 0; i < rp; i++ {
-			if (*v)[y][x+i].ZIndex <= zindx {
-				(*v)[y][x-i].Style = NewStyle(fg, bg)
-				(*v)[y][x+i].Content = r
-				(*v)[y][x+i].ZIndex = zindx
+				v.PrintRune(x+j*w, y+i, style, zindx, el, r)
 			}
+		default:
+			v.PrintRune(x+i*w, y, style, zindx, el, r)
 		}
 	}
 }
 
+// put sets the cells of the cluster g of width w at x, y unless a cell of a
+// higher z-index is there. A wide cluster that would cover one is replaced
+// by a space.
+func (v *View) put(x, y int, style Style, zindx uint8, el *dom.Element, g string, w int) {
+	row := (*v)[y]
+	if x+w > len(row) || row[x].ZIndex > zindx {
+		return
+	}
+	if w == 2 && row[x+1].ZIndex > zindx {
+		g, w = " ", 1
+	}
+	row.split(x)
+	row.split(x + w)
+	for i := 0; i < w; i++ {
+		c := row[x+i]
+		c.Style, c.ZIndex, c.Element = style, zindx, el
+		c.Content = ""
+	}
+	row[x].Content = g
+}
+
+// split replaces a wide cluster that spans the cells x-1 and x by spaces,
+// so that no half of it is left when the other is drawn over.
+func (cl CellList) split(x int) {
+	if x <= 0 || x >= len(cl) || !cl[x].Continuation() {
+		return
+	}
+	cl[x-1].Content, cl[x].Content = " ", " "
+}
+
 func (v *View) Slice(x, y, l int) CellList {
 	// fmt.Println(x, y, l)
 	return (*v)[y][x : x+l]
@@ -124,30 +154,60 @@ func (v *View) Slice(x, y, l int) CellList {
 func (v *View) Render() {
 	for _, row := range *v {
 		for _, cell := range row {
-			fmt.Print(string(cell.Content))
+			fmt.Print(cell.Content)
 		}
 	}
 }
 
+// ClearBoundry blanks the cells within bndr. Only the part of bndr inside
+// the view is cleared, since an element may still carry the boundary it
+// was laid out with before the view shrank.
 func (v *View) ClearBoundry(bndr dom.Boundry) {
+	bndr = v.clip(bndr)
 	for y := bndr.FirstY; y < bndr.SecondY; y++ {
+		(*v)[y].split(bndr.FirstX)
+		(*v)[y].split(bndr.SecondX)
 		for x := bndr.FirstX; x < bndr.SecondX; x++ {
-			(*v)[y][x].Content = ' '
-			(*v)[y][x].Style = NewStyle(0, 0)
+			(*v)[y][x].Content = " "
+			(*v)[y][x].Style = Style{}
 			(*v)[y][x].ZIndex = 0
+			(*v)[y][x].Element = nil
 		}
 	}
 }
 
-func (v *View) FillBoundry(fg, bg int, bndr dom.Boundry) {
-	s := NewStyle(fg, bg)
+func (v *View) FillBoundry(style Style, bndr dom.Boundry) {
+	bndr = v.clip(bndr)
 	for y := bndr.FirstY; y < bndr.SecondY; y++ {
 		for x := bndr.FirstX; x < bndr.SecondX; x++ {
-			(*v)[y][x].Style = s
+			(*v)[y][x].Style = style
 		}
 	}
 }
 
+// clip returns the part of bndr inside the view.
+func (v *View) clip(bndr dom.Boundry) dom.Boundry {
+	var w, h int
+	if len(*v) != 0 {
+		w, h = int(v.Width()), int(v.Height())
+	}
+	bndr.FirstX = clampInt(bndr.FirstX, 0, w)
+	bndr.SecondX = clampInt(bndr.SecondX, 0, w)
+	bndr.FirstY = clampInt(bndr.FirstY, 0, h)
+	bndr.SecondY = clampInt(bndr.SecondY, 0, h)
+	return bndr
+}
+
+func clampInt(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+
 func (v *View) Resize(width, height int) {
 	//v = NewView(int64(width), int64(height))
 	if len(*v) < height {
@@ -0,0 +1,249 @@
+package view
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --------------------
+//   Text Width
+// --------------------
+
+// RuneWidth returns the number of columns r takes on a terminal: 0 for
+// combining marks and other characters drawn over the one before, 2 for
+// wide East Asian characters and emoji, and 1 for the rest.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < 0x300:
+		// Latin, the common case
+		if r >= 0x80 && r < 0xa0 || r == 0xad {
+			return 0
+		}
+		return 1
+	case isZeroWidth(r):
+		return 0
+	case inTable(r, wideTable):
+		return 2
+	}
+	return 1
+}
+
+// StringWidth returns the number of columns s takes on a terminal.
+func StringWidth(s string) int {
+	var width int
+	for s != "" {
+		g, w := FirstGrapheme(s)
+		s = s[len(g):]
+		width += w
+	}
+	return width
+}
+
+// CutWidth splits s after the grapheme clusters that fit in width columns.
+// The head holds at least one cluster, so that cutting again and again
+// always comes to an end.
+func CutWidth(s string, width int) (string, string) {
+	var n, col int
+	for n < len(s) {
+		g, w := FirstGrapheme(s[n:])
+		if col+w > width && n > 0 {
+			break
+		}
+		n += len(g)
+		col += w
+	}
+	return s[:n], s[n:]
+}
+
+// FirstGrapheme returns the first grapheme cluster of s, the characters
+// that are drawn as one, e.g. a letter and its accents, a flag or an emoji
+// joined with others, and the number of columns it takes. The rules of
+// Unicode Standard Annex #29 are followed but those for the scripts of
+// India.
+func FirstGrapheme(s string) (string, int) {
+	if s == "" {
+		return "", 0
+	}
+	first, n := utf8.DecodeRuneInString(s)
+	prev, pictographic, regional := first, isPictographic(first), 0
+	if isRegionalIndicator(first) {
+		regional = 1
+	}
+	emoji := false
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		switch {
+		case prev == '\r' && r == '\n':
+			// CR LF
+		case isControl(prev) || isControl(r):
+			return s[:n], clusterWidth(first, emoji, regional)
+		case hangulJoins(prev, r):
+		case isExtend(r) || r == zwj || unicode.Is(unicode.Mc, r):
+			if r == 0xfe0f {
+				emoji = true
+			}
+		case prev == zwj && pictographic && isPictographic(r):
+		case regional%2 == 1 && isRegionalIndicator(r):
+			regional++
+		default:
+			return s[:n], clusterWidth(first, emoji, regional)
+		}
+		prev = r
+		n += size
+	}
+	return s, clusterWidth(first, emoji, regional)
+}
+
+const zwj = 0x200d
+
+// clusterWidth returns the width of a grapheme cluster starting with first.
+// Emoji presentation selectors widen the characters they follow and pairs
+// of regional indicators make flags.
+func clusterWidth(first rune, emoji bool, regional int) int {
+	w := RuneWidth(first)
+	if w == 1 && (emoji && isPictographic(first) || regional > 1) {
+		return 2
+	}
+	return w
+}
+
+func isZeroWidth(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) ||
+		r >= 0x1160 && r <= 0x11ff || // Hangul vowels and final consonants
+		r >= 0xd7b0 && r <= 0xd7ff
+}
+
+func isControl(r rune) bool {
+	switch {
+	case r == zwj || r == 0x200c:
+		return false
+	case r >= 0xe0020 && r <= 0xe007f:
+		// tags, which follow emoji
+		return false
+	}
+	return unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp)
+}
+
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me) ||
+		r == 0x200c ||
+		r >= 0x1f3fb && r <= 0x1f3ff || // emoji modifiers
+		r >= 0xe0020 && r <= 0xe007f
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isPictographic(r rune) bool {
+	return r >= 0xa9 && inTable(r, pictographicTable)
+}
+
+// hangulJoins tells whether the Hangul jamo or syllables prev and r are
+// parts of one syllable.
+func hangulJoins(prev, r rune) bool {
+	p, n := hangulType(prev), hangulType(r)
+	switch p {
+	case hangulL:
+		return n != hangulNone
+	case hangulV, hangulLV:
+		return n == hangulV || n == hangulT
+	case hangulT, hangulLVT:
+		return n == hangulT
+	}
+	return false
+}
+
+const (
+	hangulNone = iota
+	hangulL
+	hangulV
+	hangulT
+	hangulLV
+	hangulLVT
+)
+
+func hangulType(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115f, r >= 0xa960 && r <= 0xa97c:
+		return hangulL
+	case r >= 0x1160 && r <= 0x11a7, r >= 0xd7b0 && r <= 0xd7c6:
+		return hangulV
+	case r >= 0x11a8 && r <= 0x11ff, r >= 0xd7cb && r <= 0xd7fb:
+		return hangulT
+	case r >= 0xac00 && r <= 0xd7a3:
+		if (r-0xac00)%28 == 0 {
+			return hangulLV
+		}
+		return hangulLVT
+	}
+	return hangulNone
+}
+
+// runeRange is an inclusive range of runes.
+type runeRange struct {
+	lo, hi rune
+}
+
+func inTable(r rune, table []runeRange) bool {
+	i := sort.Search(len(table), func(i int) bool { return table[i].hi >= r })
+	return i < len(table) && table[i].lo <= r
+}
+
+// wideTable holds the East Asian wide and fullwidth characters and the
+// emoji shown as such by default, from Unicode 15.
+var wideTable = []runeRange{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x2e99},
+	{0x2e9b, 0x2ef3}, {0x2f00, 0x2fd5}, {0x2ff0, 0x2ffb}, {0x3000, 0x303e},
+	{0x3041, 0x3096}, {0x3099, 0x30ff}, {0x3105, 0x312f}, {0x3131, 0x318e},
+	{0x3190, 0x31e3}, {0x31f0, 0x321e}, {0x3220, 0x3247}, {0x3250, 0x4dbf},
+	{0x4e00, 0xa48c}, {0xa490, 0xa4c6}, {0xa960, 0xa97c}, {0xac00, 0xd7a3},
+	{0xf900, 0xfaff}, {0xfe10, 0xfe19}, {0xfe30, 0xfe52}, {0xfe54, 0xfe66},
+	{0xfe68, 0xfe6b}, {0xff01, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x16ff0, 0x16ff1}, {0x17000, 0x187f7}, {0x18800, 0x18cd5}, {0x18d00, 0x18d08},
+	{0x1aff0, 0x1affe}, {0x1b000, 0x1b122}, {0x1b132, 0x1b132}, {0x1b150, 0x1b152},
+	{0x1b155, 0x1b155}, {0x1b164, 0x1b167}, {0x1b170, 0x1b2fb}, {0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f202},
+	{0x1f210, 0x1f23b}, {0x1f240, 0x1f248}, {0x1f250, 0x1f251}, {0x1f260, 0x1f265},
+	{0x1f300, 0x1f320}, {0x1f32d, 0x1f335}, {0x1f337, 0x1f37c}, {0x1f37e, 0x1f393},
+	{0x1f3a0, 0x1f3ca}, {0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e}, {0x1f440, 0x1f440}, {0x1f442, 0x1f4fc}, {0x1f4ff, 0x1f53d},
+	{0x1f54b, 0x1f54e}, {0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4}, {0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc},
+	{0x1f6d0, 0x1f6d2}, {0x1f6d5, 0x1f6d7}, {0x1f6dc, 0x1f6df}, {0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb}, {0x1f7f0, 0x1f7f0}, {0x1f90c, 0x1f93a},
+	{0x1f93c, 0x1f945}, {0x1f947, 0x1f9ff}, {0x1fa70, 0x1fa7c}, {0x1fa80, 0x1fa88},
+	{0x1fa90, 0x1fabd}, {0x1fabf, 0x1fac5}, {0x1face, 0x1fadb}, {0x1fae0, 0x1fae8},
+	{0x1faf0, 0x1faf8}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// pictographicTable holds the characters that emoji sequences are made
+// of, roughly the Extended_Pictographic property.
+var pictographicTable = []runeRange{
+	{0x00a9, 0x00a9}, {0x00ae, 0x00ae}, {0x203c, 0x203c}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21a9, 0x21aa},
+	{0x231a, 0x231b}, {0x2328, 0x2328}, {0x2388, 0x2388}, {0x23cf, 0x23cf},
+	{0x23e9, 0x23f3}, {0x23f8, 0x23fa}, {0x24c2, 0x24c2}, {0x25aa, 0x25ab},
+	{0x25b6, 0x25b6}, {0x25c0, 0x25c0}, {0x25fb, 0x25fe}, {0x2600, 0x27bf},
+	{0x2934, 0x2935}, {0x2b05, 0x2b07}, {0x2b1b, 0x2b1c}, {0x2b50, 0x2b50},
+	{0x2b55, 0x2b55}, {0x3030, 0x3030}, {0x303d, 0x303d}, {0x3297, 0x3297},
+	{0x3299, 0x3299}, {0x1f000, 0x1f0ff}, {0x1f10d, 0x1f10f}, {0x1f12f, 0x1f12f},
+	{0x1f16c, 0x1f171}, {0x1f17e, 0x1f17f}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a},
+	{0x1f1ad, 0x1f1e5}, {0x1f201, 0x1f20f}, {0x1f21a, 0x1f21a}, {0x1f22f, 0x1f22f},
+	{0x1f232, 0x1f23a}, {0x1f23c, 0x1f23f}, {0x1f249, 0x1f3fa}, {0x1f400, 0x1f53d},
+	{0x1f546, 0x1f64f}, {0x1f680, 0x1f6ff}, {0x1f774, 0x1f77f}, {0x1f7d5, 0x1f7ff},
+	{0x1f80c, 0x1f80f}, {0x1f848, 0x1f84f}, {0x1f85a, 0x1f85f}, {0x1f888, 0x1f88f},
+	{0x1f8ae, 0x1f8ff}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945}, {0x1f947, 0x1faff},
+	{0x1fc00, 0x1fffd},
+}
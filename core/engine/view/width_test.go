@@ -0,0 +1,104 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"gotest.tools/v3/assert"
+)
+
+func TestFirstGrapheme(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		input   string
+		cluster string
+		width   int
+	}{
+		{"ascii", "ab", "a", 1},
+		{"combining accent", "éx", "é", 1},
+		{"cjk", "漢字", "漢", 2},
+		{"fullwidth", "Ａb", "Ａ", 2},
+		{"emoji", "😀!", "😀", 2},
+		{"skin tone", "👍🏽 ok", "👍🏽", 2},
+		{"zwj family", "👨‍👩‍👧x", "👨‍👩‍👧", 2},
+		{"flag", "🇮🇷🇩🇪", "🇮🇷", 2},
+		{"emoji presentation", "❤️.", "❤️", 2},
+		{"text presentation", "❤.", "❤", 1},
+		{"hangul jamo", "각x", "각", 2},
+		{"crlf", "\r\nx", "\r\n", 0},
+		{"stray combining mark", "\u0301a", "\u0301", 0},
+		{"empty", "", "", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster, width := FirstGrapheme(tc.input)
+			assert.Equal(t, cluster, tc.cluster)
+			assert.Equal(t, width, tc.width)
+		})
+	}
+	assert.Equal(t, StringWidth("naïve 日本 🇯🇵"), 13)
+}
+
+func TestCutWidth(t *testing.T) {
+	head, tail := CutWidth("ab漢字", 3)
+	assert.Equal(t, head, "ab")
+	assert.Equal(t, tail, "漢字")
+
+	head, tail = CutWidth("漢字", 1)
+	assert.Equal(t, head, "漢", "the head holds at least one cluster")
+	assert.Equal(t, tail, "字")
+}
+
+func TestPrintTextWide(t *testing.T) {
+	v := NewView(6, 1)
+	row := func() (s string) {
+		for _, c := range (*v)[0] {
+			if c.Continuation() {
+				s += "_"
+			}
+			s += c.Content
+		}
+		return s
+	}
+
+	n := v.PrintText(0, 0, 5, Style{}, 0, nil, "a漢字b")
+	assert.Equal(t, n, 5)
+	assert.Equal(t, row(), "a漢_字_ ", "a wide cluster takes a continuation cell")
+
+	v.PrintText(0, 0, 6, Style{}, 0, nil, "ab漢字")
+	assert.Equal(t, row(), "ab漢_字_")
+
+	// drawing over one half of a wide cluster blanks the other
+	v.PrintRune(3, 0, Style{}, 0, nil, 'x')
+	assert.Equal(t, row(), "ab x字_")
+	v.PrintRune(4, 0, Style{}, 0, nil, 'y')
+	assert.Equal(t, row(), "ab xy ")
+
+	// a wide cluster is not cut in half by the width
+	v.Flush()
+	n = v.PrintText(1, 0, 2, Style{}, 0, nil, "a漢")
+	assert.Equal(t, n, 2)
+	assert.Equal(t, row(), " a    ")
+	v.PrintString(4, 0, Style{}, 0, nil, "字字")
+	assert.Equal(t, row(), " a  字_", "nor by the edge of the view")
+
+	// a cell of a higher z-index is never drawn over, even by half a cluster
+	v.Flush()
+	v.PrintRune(2, 0, Style{}, 1, nil, '|')
+	v.PrintText(0, 0, 6, NewStyle(dom.PaletteColor(1), dom.ColorDefault), 0, nil, "a漢字")
+	assert.Equal(t, row(), "a |字_ ")
+	assert.Equal(t, (*v)[0][1].Style, NewStyle(dom.PaletteColor(1), dom.ColorDefault))
+}
+
+func TestFrontDiffWide(t *testing.T) {
+	v := NewView(4, 1)
+	f := NewFront(4, 1)
+	v.PrintString(0, 0, Style{}, 0, nil, "漢字")
+	f.Diff(v)
+
+	// only the continuation of a cluster changed: the run starts with it
+	(*v)[0][3].Style = NewStyle(dom.PaletteColor(1), dom.ColorDefault)
+	runs := f.Diff(v)
+	assert.Equal(t, len(runs), 1)
+	assert.Equal(t, runs[0].X, 2)
+	assert.Equal(t, runs[0].Cells[0].Content, "字")
+}
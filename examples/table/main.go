@@ -23,7 +23,7 @@ type Application struct {
 	modal    bool
 }
 
-func NewApplication() *Application {
+func NewApplication(opts ...engine.EngineOpt) *Application {
 	var a Application
 
 	tf, err := os.Open("table.html")
@@ -31,7 +31,7 @@ func NewApplication() *Application {
 		panic(err)
 	}
 
-	a.eng, err = engine.NewEngineFromTemplate(tf)
+	a.eng, err = engine.NewEngineFromTemplate(tf, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -46,14 +46,21 @@ func (a *Application) Start() {
 }
 
 func (a *Application) eventWatcher() {
-	var event tty.Event
-	for event = range a.eng.PollEvent() {
-		switch event.Type() {
-		case tty.EventType_Keyboard:
-			a.parseKeyboardEvent(event.(tty.KeyboardEvent))
-		case tty.EventType_Resize:
-			a.eng.Reload()
-		}
+	for event := range a.eng.PollEvent() {
+		a.eng.Do(func() { a.handleEvent(event) })
+	}
+}
+
+// handleEvent runs the action bound to event. It changes the document, so
+// it runs within Engine.Do.
+func (a *Application) handleEvent(event tty.Event) {
+	switch event.Type() {
+	case tty.EventType_Keyboard:
+		a.parseKeyboardEvent(event.(tty.KeyboardEvent))
+	case tty.EventType_Mouse:
+		a.parseMouseEvent(event)
+	case tty.EventType_Resize:
+		a.eng.Reload()
 	}
 }
 
@@ -67,13 +74,27 @@ func (a *Application) parseKeyboardEvent(event tty.KeyboardEvent) {
 		a.SelectNext()
 	case event.Is(tty.SpecialKey_Up):
 		a.SelectPrevious()
-	case event.Is(tty.NewByteKey('\r')):
+	case event.Is(tty.SpecialKey_Enter):
 		a.ToggleModal(fmt.Sprintf("you have selected row %d", a.selected))
 	case event.Is(tty.NewByteKey('q')):
 		a.eng.Exit()
 	}
 }
 
+func (a *Application) parseMouseEvent(event tty.Event) {
+	switch event := event.(type) {
+	case engine.ClickEvent:
+		a.SelectElement(event.Target.Element())
+	case tty.MouseEvent:
+		switch event.Button {
+		case tty.MouseButton_WheelDown:
+			a.SelectNext()
+		case tty.MouseButton_WheelUp:
+			a.SelectPrevious()
+		}
+	}
+}
+
 func (a *Application) ToggleModal(s string) {
 	if a.modal {
 		a.HideModal()
@@ -90,7 +111,7 @@ func (a *Application) HideModal() {
 
 func (a *Application) DrawModal(text string) {
 	bnd := a.eng.GetElementByID("body")[0].Element().Boundry
-	e := dom.MustParseElementFromString(fmt.Sprintf(`<div display="absolute" id="modal" z-index="3" height="10" width="40" background-color="0" left="%d" top="%d" border="true">%s</div>`, bnd.Width()/2-20, bnd.Height()/2-5, text))
+	e := dom.MustParseElementFromString(fmt.Sprintf(`<div display="absolute" id="modal" z-index="3" height="10" width="40" background-color="default" left="%d" top="%d" border="true">%s</div>`, bnd.Width()/2-20, bnd.Height()/2-5, text))
 	a.eng.GetElementByID("body")[0].PrependChild(e)
 	a.modal = true
 }
@@ -105,13 +126,9 @@ func (a *Application) SelectPrevious() {
 		a.ScrollTop()
 	}
 
-	tb.Children[a.selected].Attrs.BackGroundColor = 0
-	tb.Children[a.selected].InheritChildrensAttr()
-	a.eng.Update(tb.Children[a.selected])
+	a.eng.ElementorOf(tb.Children[a.selected]).SetAttribute("background-color", "default")
 	a.selected--
-	tb.Children[a.selected].Attrs.BackGroundColor = 56
-	tb.Children[a.selected].InheritChildrensAttr()
-	a.eng.Update(tb.Children[a.selected])
+	a.eng.ElementorOf(tb.Children[a.selected]).SetAttribute("background-color", "56")
 }
 
 func (a *Application) SelectNext() {
@@ -124,13 +141,29 @@ func (a *Application) SelectNext() {
 		a.ScrollDown()
 	}
 
-	tb.Children[a.selected].Attrs.BackGroundColor = 0
-	tb.Children[a.selected].InheritChildrensAttr()
-	a.eng.Update(tb.Children[a.selected])
+	a.eng.ElementorOf(tb.Children[a.selected]).SetAttribute("background-color", "default")
 	a.selected++
-	tb.Children[a.selected].Attrs.BackGroundColor = 56
-	tb.Children[a.selected].InheritChildrensAttr()
-	a.eng.Update(tb.Children[a.selected])
+	a.eng.ElementorOf(tb.Children[a.selected]).SetAttribute("background-color", "56")
+}
+
+// SelectElement selects the table row that contains el, if any.
+func (a *Application) SelectElement(el *dom.Element) {
+	tb := a.eng.GetElementByID("table-body")[0].Element()
+	for ; el != nil && el.Parent != tb; el = el.Parent {
+	}
+	if el == nil {
+		return
+	}
+
+	for i, row := range tb.Children {
+		if row != el || i == a.selected {
+			continue
+		}
+		a.eng.ElementorOf(tb.Children[a.selected]).SetAttribute("background-color", "default")
+		a.selected = i
+		a.eng.ElementorOf(row).SetAttribute("background-color", "56")
+		return
+	}
 }
 
 func (a *Application) ScrollDown() {
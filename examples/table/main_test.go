@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/samtuitest"
+)
+
+func waitForBackground(t *testing.T, vt *tty.VirtualTTY, x, y int, bg dom.Color) {
+	t.Helper()
+	deadline := time.Now().Add(samtuitest.DefaultTimeout)
+	for vt.CellAt(x, y).Background != bg {
+		if time.Now().After(deadline) {
+			t.Fatalf("cell %d,%d never got background %v", x, y, bg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// rowY returns the screen row of the i-th row of the table.
+func rowY(app *Application, i int) (y int) {
+	app.eng.Do(func() {
+		tb := app.eng.GetElementByID("table-body")[0].Element()
+		y = tb.Children[i].Boundry.FirstY
+	})
+	return y
+}
+
+func TestTableScript(t *testing.T) {
+	vt := tty.NewVirtualTTY(60, 20)
+	app := NewApplication(engine.WithTTY(vt))
+	d := samtuitest.NewDriver(app.eng, vt)
+
+	done := make(chan struct{})
+	go func() {
+		app.Start()
+		close(done)
+	}()
+
+	if err := d.WaitForText("insert new row", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SendKeys("i", "i", "i"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WaitForText("row 2", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SendKeys("down"); err != nil {
+		t.Fatal(err)
+	}
+	waitForBackground(t, vt, 0, rowY(app, 1), dom.PaletteColor(56))
+
+	if err := d.SendKeys("enter"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WaitForText("you have selected row 1", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.WaitForElement("#modal"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Click("#table-body > trow"); err != nil {
+		t.Fatal(err)
+	}
+	waitForBackground(t, vt, 0, rowY(app, 0), dom.PaletteColor(56))
+	waitForBackground(t, vt, 0, rowY(app, 1), dom.ColorDefault)
+
+	if err := d.SendKeys("q"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("application did not exit")
+	}
+}
@@ -0,0 +1,300 @@
+package samtuitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine"
+	"github.com/saman3d/samtui/core/engine/tty"
+)
+
+var (
+	ErrTimeout    = errors.New("timed out")
+	ErrUnknownKey = errors.New("unknown key")
+)
+
+// DefaultTimeout is how long a Driver waits for the screen when no timeout
+// is given.
+const DefaultTimeout = 2 * time.Second
+
+// --------------------
+//       Driver
+// --------------------
+
+// Driver scripts a running engine through a virtual terminal: it types and
+// clicks like a user would and waits for the screen to show the outcome.
+type Driver struct {
+	Engine *engine.Engine
+	TTY    *tty.VirtualTTY
+	// Timeout bounds Click and WaitForElement.
+	Timeout time.Duration
+
+	done chan error
+}
+
+// NewDriver drives eng, which must render to vt. The caller starts the
+// engine and reads its events, usually by starting the application under
+// test.
+func NewDriver(eng *engine.Engine, vt *tty.VirtualTTY) *Driver {
+	return &Driver{
+		Engine:  eng,
+		TTY:     vt,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// Start loads template into an engine on a virtual terminal of the given
+// size and runs it. Events that reach PollEvent are discarded, except for
+// resizes which reload the view.
+func Start(template string, width, height int) (*Driver, error) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(template))
+	if err != nil {
+		return nil, err
+	}
+	vt := tty.NewVirtualTTY(width, height)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewDriver(eng, vt)
+	d.done = make(chan error, 1)
+	go func() {
+		for ev := range eng.PollEvent() {
+			if ev.Type() == tty.EventType_Resize {
+				eng.ReloadNow()
+			}
+		}
+	}()
+	go func() { d.done <- eng.Start(context.Background()) }()
+	return d, nil
+}
+
+// Stop exits the engine and, when it was started by Start, waits for it to
+// return.
+func (d *Driver) Stop() {
+	d.Engine.Exit()
+	if d.done != nil {
+		<-d.done
+	}
+}
+
+// --------------------
+//       Input
+// --------------------
+
+// SendKeys presses each of keys in turn. A key is a character or a name
+// such as "enter", "tab", "up" or "f5", optionally prefixed with modifiers,
+// e.g. "ctrl+c", "alt+b" or "shift+up".
+func (d *Driver) SendKeys(keys ...string) error {
+	for _, key := range keys {
+		b, err := keyBytes(key)
+		if err != nil {
+			return err
+		}
+		d.TTY.SendBytes(b)
+	}
+	return nil
+}
+
+// Type presses a key for every character of s.
+func (d *Driver) Type(s string) {
+	for _, r := range s {
+		d.TTY.SendBytes([]byte(string(r)))
+	}
+}
+
+// Paste pastes text as a terminal in bracketed paste mode would.
+func (d *Driver) Paste(text string) {
+	d.TTY.SendPaste(text)
+}
+
+// Click waits for the first element matching selector to be painted, then
+// presses and releases the left button over it.
+func (d *Driver) Click(selector string) error {
+	el, err := d.WaitForElement(selector)
+	if err != nil {
+		return err
+	}
+	var x, y int
+	err = d.poll(fmt.Sprintf("element %q to be painted", selector), func() bool {
+		var ok bool
+		d.Engine.Do(func() { x, y, ok = d.paintedCell(el.Element()) })
+		return ok
+	}, d.Timeout)
+	if err != nil {
+		return err
+	}
+	d.TTY.SendMouse(tty.MouseEvent{X: x, Y: y, Button: tty.MouseButton_Left, Action: tty.MouseAction_Press})
+	d.TTY.SendMouse(tty.MouseEvent{X: x, Y: y, Button: tty.MouseButton_Left, Action: tty.MouseAction_Release})
+	return nil
+}
+
+// paintedCell finds a cell of the screen painted by el or one of its
+// descendants. It reads the view, so it runs within Engine.Do.
+func (d *Driver) paintedCell(el *dom.Element) (int, int, bool) {
+	b := el.Boundry
+	for y := b.FirstY; y < b.SecondY; y++ {
+		for x := b.FirstX; x < b.SecondX; x++ {
+			hit := d.Engine.ElementAt(x, y)
+			if hit == nil {
+				continue
+			}
+			for p := hit.Element(); p != nil; p = p.Parent {
+				if p == el {
+					return x, y, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// --------------------
+//      Waiting
+// --------------------
+
+// WaitForText waits until s appears on the screen.
+func (d *Driver) WaitForText(s string, timeout time.Duration) error {
+	return d.poll(fmt.Sprintf("text %q", s), func() bool {
+		return strings.Contains(d.ScreenText(), s)
+	}, timeout)
+}
+
+// WaitForElement waits until an element matching selector is in the
+// document and returns the first one. Like any Elementor used outside of
+// event listeners, it is to be changed within Engine.Do.
+func (d *Driver) WaitForElement(selector string) (engine.Elementor, error) {
+	var el engine.Elementor
+	var qerr error
+	err := d.poll(fmt.Sprintf("element %q", selector), func() bool {
+		d.Engine.Do(func() { el, qerr = d.Engine.QuerySelector(selector) })
+		return qerr != nil || el != nil
+	}, d.Timeout)
+	if qerr != nil {
+		return nil, qerr
+	}
+	return el, err
+}
+
+// ScreenText returns the rows of the screen with trailing spaces removed.
+func (d *Driver) ScreenText() string {
+	return d.TTY.Text()
+}
+
+func (d *Driver) poll(what string, cond func() bool, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w waiting %s for %s; screen:\n%s", ErrTimeout, timeout, what, d.ScreenText())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return nil
+}
+
+// --------------------
+//      Key Names
+// --------------------
+
+var keyNames = map[string]string{
+	"enter":     string(tty.SpecialKey_Enter),
+	"tab":       string(tty.SpecialKey_Tab),
+	"esc":       "\x1b",
+	"escape":    "\x1b",
+	"space":     " ",
+	"backspace": string(tty.SpecialKey_Backspace),
+	"up":        string(tty.SpecialKey_Up),
+	"down":      string(tty.SpecialKey_Down),
+	"right":     string(tty.SpecialKey_Right),
+	"left":      string(tty.SpecialKey_Left),
+	"insert":    string(tty.SpecialKey_Insert),
+	"delete":    string(tty.SpecialKey_Delete),
+	"home":      string(tty.SpecialKey_Home),
+	"end":       string(tty.SpecialKey_End),
+	"pageup":    string(tty.SpecialKey_PageUp),
+	"pagedown":  string(tty.SpecialKey_PageDown),
+	"f1":        string(tty.SpecialKey_F1),
+	"f2":        string(tty.SpecialKey_F2),
+	"f3":        string(tty.SpecialKey_F3),
+	"f4":        string(tty.SpecialKey_F4),
+	"f5":        string(tty.SpecialKey_F5),
+	"f6":        string(tty.SpecialKey_F6),
+	"f7":        string(tty.SpecialKey_F7),
+	"f8":        string(tty.SpecialKey_F8),
+	"f9":        string(tty.SpecialKey_F9),
+	"f10":       string(tty.SpecialKey_F10),
+	"f11":       string(tty.SpecialKey_F11),
+	"f12":       string(tty.SpecialKey_F12),
+}
+
+// keyBytes returns what a terminal sends for key.
+func keyBytes(key string) ([]byte, error) {
+	parts := strings.Split(key, "+")
+	name := parts[len(parts)-1]
+	if name == "" && len(parts) > 1 {
+		// "ctrl++" presses the plus key
+		name = "+"
+		parts = parts[:len(parts)-1]
+	}
+	var ctrl, alt, shift bool
+	for _, m := range parts[:len(parts)-1] {
+		switch strings.ToLower(m) {
+		case "ctrl":
+			ctrl = true
+		case "alt":
+			alt = true
+		case "shift":
+			shift = true
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+		}
+	}
+
+	lower := strings.ToLower(name)
+	seq, named := keyNames[lower]
+	switch {
+	case named && len(seq) == 3 && seq[:2] == "\x1b[" && (ctrl || alt || shift):
+		// xterm encodes modified arrows as CSI 1;<mod> <final>
+		m := 1
+		if shift {
+			m++
+		}
+		if alt {
+			m += 2
+		}
+		if ctrl {
+			m += 4
+		}
+		return []byte(fmt.Sprintf("\x1b[1;%d%c", m, seq[2])), nil
+	case named && lower == "tab" && shift && !ctrl && !alt:
+		return []byte(tty.SpecialKey_ShiftTab), nil
+	case named && (ctrl || shift):
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	case named:
+		// sent as is
+	case len([]rune(name)) != 1:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	case ctrl:
+		c := lower[0]
+		if c < 'a' || c > 'z' {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+		}
+		seq = string([]byte{c - 'a' + 1})
+	case shift:
+		seq = strings.ToUpper(name)
+	default:
+		seq = name
+	}
+	if alt {
+		seq = "\x1b" + seq
+	}
+	return []byte(seq), nil
+}
@@ -0,0 +1,95 @@
+package samtuitest
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type keyBytesTestSuite struct {
+	key      string
+	expected string
+	err      error
+}
+
+var keyBytesTestSuites = []keyBytesTestSuite{
+	{key: "a", expected: "a"},
+	{key: "enter", expected: "\r"},
+	{key: "ctrl+c", expected: "\x03"},
+	{key: "alt+b", expected: "\x1bb"},
+	{key: "shift+a", expected: "A"},
+	{key: "ctrl++", err: ErrUnknownKey},
+	{key: "shift+tab", expected: "\x1b[Z"},
+	{key: "Shift+Tab", expected: "\x1b[Z"},
+	{key: "ctrl+left", expected: "\x1b[1;5D"},
+	{key: "shift+alt+up", expected: "\x1b[1;4A"},
+	{key: "f5", expected: "\x1b[15~"},
+	{key: "hyper+a", err: ErrUnknownKey},
+	{key: "nope", err: ErrUnknownKey},
+	{key: "ctrl+enter", err: ErrUnknownKey},
+}
+
+func TestKeyBytes(t *testing.T) {
+	for _, suite := range keyBytesTestSuites {
+		t.Run(suite.key, func(t *testing.T) {
+			b, err := keyBytes(suite.key)
+			if !errors.Is(err, suite.err) {
+				t.Fatalf("expected error %v, got %v", suite.err, err)
+			}
+			if string(b) != suite.expected {
+				t.Errorf("expected %q, got %q", suite.expected, b)
+			}
+		})
+	}
+}
+
+var driverTemplate = `<html>
+<body display="flex" flex-direction="column">
+	<div display="flex" height="1">
+		<p id="first" writable="true"></p>
+		<p id="second" writable="true"></p>
+	</div>
+	<div></div>
+</body>
+</html>`
+
+func TestDriver(t *testing.T) {
+	d, err := Start(driverTemplate, 20, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	if err := d.Click("#second"); err != nil {
+		t.Fatal(err)
+	}
+	d.Type("hello")
+	if err := d.WaitForText("          hello", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SendKeys("shift+tab"); err != nil {
+		t.Fatal(err)
+	}
+	d.Type("hi")
+	if err := d.WaitForText("hi        hello", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SendKeys("ctrl+u"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WaitForText("          hello", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Paste("q\nw")
+	if err := d.WaitForText("q w       hello", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Timeout = 20 * time.Millisecond
+	if _, err := d.WaitForElement("#missing"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package samtuitest renders templates on a virtual terminal and compares
+// the result with golden files.
+//
+// Golden files live in the testdata directory of the calling package and are
+// rewritten when the tests run with the -update flag:
+//
+//	go test ./... -update
+package samtuitest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saman3d/samtui/core/dom"
+	"github.com/saman3d/samtui/core/engine"
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/core/engine/view"
+)
+
+var update = flag.Bool("update", false, "rewrite samtuitest golden files")
+
+// --------------------
+//       Screen
+// --------------------
+
+// Screen is a rendered frame in two forms: the plain characters and the
+// characters annotated with the escape sequences of their colors.
+type Screen struct {
+	// Text holds one line per row with trailing spaces removed.
+	Text string
+	// ANSI holds one line per row with SGR sequences wherever the style
+	// changes. Each row ends with the style reset.
+	ANSI string
+}
+
+// Render loads template into an engine running on a virtual terminal of
+// the given size, plays script through it and returns the final screen. A
+// tty.ResizeEvent of the script resizes the terminal before it is handled.
+func Render(template string, width, height int, script ...tty.Event) (*Screen, error) {
+	doc, err := dom.NewDocumentFromReader(strings.NewReader(template))
+	if err != nil {
+		return nil, err
+	}
+
+	vt := tty.NewVirtualTTY(width, height)
+	eng, err := engine.NewEngine(doc, engine.WithTTY(vt))
+	if err != nil {
+		return nil, err
+	}
+
+	eng.Update(doc.Body)
+	eng.Flush()
+	for _, ev := range script {
+		if re, ok := ev.(tty.ResizeEvent); ok {
+			vt.SetSize(re.Width, re.Height)
+		}
+		if ev, ok := eng.HandleEvent(ev); ok && ev.Type() == tty.EventType_Resize {
+			eng.ReloadNow()
+		}
+		eng.Flush()
+	}
+
+	return ScreenOf(vt), nil
+}
+
+// ScreenOf captures the current screen of vt.
+func ScreenOf(vt *tty.VirtualTTY) *Screen {
+	w, h, _ := vt.WindowSize()
+	var ansi strings.Builder
+	for y := 0; y < h; y++ {
+		var style view.Style
+		for x := 0; x < w; x++ {
+			c := vt.CellAt(x, y)
+			if c.Style != style {
+				ansi.WriteString(c.Style.String())
+				style = c.Style
+			}
+			ansi.WriteString(c.Content)
+		}
+		ansi.WriteString("\033[0m\n")
+	}
+	return &Screen{
+		Text: vt.Text() + "\n",
+		ANSI: ansi.String(),
+	}
+}
+
+// --------------------
+//    Golden Files
+// --------------------
+
+// AssertGolden compares s with testdata/<name>.txt and testdata/<name>.ansi,
+// or writes them when the -update flag is set.
+func AssertGolden(t testing.TB, name string, s *Screen) {
+	t.Helper()
+	assertGoldenFile(t, filepath.Join("testdata", name+".txt"), s.Text)
+	assertGoldenFile(t, filepath.Join("testdata", name+".ansi"), s.ANSI)
+}
+
+// Snapshot renders template with Render and checks the result against the
+// golden files of name.
+func Snapshot(t testing.TB, name, template string, width, height int, script ...tty.Event) {
+	t.Helper()
+	s, err := Render(template, width, height, script...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertGolden(t, name, s)
+}
+
+func assertGoldenFile(t testing.TB, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	if diff := lineDiff(string(want), got); diff != "" {
+		t.Errorf("%s does not match the rendered screen (run the tests with -update to accept it):\n%s", path, diff)
+	}
+}
+
+// lineDiff lists the lines that differ between want and got, quoted so that
+// escape sequences and trailing spaces are visible.
+func lineDiff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wl := strings.Split(want, "\n")
+	gl := strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			fmt.Fprintf(&sb, "line %d:\n\twant %q\n\tgot  %q\n", i+1, w, g)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+package samtuitest_test
+
+import (
+	"testing"
+
+	"github.com/saman3d/samtui/core/engine/tty"
+	"github.com/saman3d/samtui/samtuitest"
+)
+
+var formTemplate = `<html>
+<head>
+	<style>
+		.label { color: 3; }
+	</style>
+</head>
+<body display="flex" flex-direction="column">
+	<div height="3" border="true">title</div>
+	<div display="flex" height="1">
+		<p class="label" width="6">name</p>
+		<p id="name" writable="true" background-color="8"></p>
+	</div>
+	<div></div>
+</body>
+</html>`
+
+type snapshotTestSuite struct {
+	name   string
+	width  int
+	height int
+	script []tty.Event
+}
+
+var snapshotTestSuites = []snapshotTestSuite{
+	{
+		name:   "form",
+		width:  20,
+		height: 6,
+	},
+	{
+		name:   "form_typed",
+		width:  20,
+		height: 6,
+		script: []tty.Event{
+			tty.KeyboardEvent{Key: tty.SpecialKey_Tab},
+			tty.KeyboardEvent{Key: tty.NewByteKey('h')},
+			tty.KeyboardEvent{Key: tty.NewByteKey('i')},
+		},
+	},
+	{
+		name:   "form_resized",
+		width:  20,
+		height: 6,
+		script: []tty.Event{
+			tty.ResizeEvent{Width: 12, Height: 5},
+		},
+	},
+}
+
+func TestSnapshot(t *testing.T) {
+	for _, suite := range snapshotTestSuites {
+		t.Run(suite.name, func(t *testing.T) {
+			samtuitest.Snapshot(t, suite.name, formTemplate, suite.width, suite.height, suite.script...)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	s, err := samtuitest.Render(`<html><body display="flex"><p color="2">hi</p></body></html>`, 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Text != "hi\n" {
+		t.Errorf("unexpected text %q", s.Text)
+	}
+	if want := "\033[0;38;5;2mhi  \033[0m\n"; s.ANSI != want {
+		t.Errorf("unexpected ansi %q, want %q", s.ANSI, want)
+	}
+}